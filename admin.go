@@ -0,0 +1,386 @@
+package main
+
+import (
+	"fmt"
+	"github.com/labstack/echo"
+	"github.com/labstack/gommon/log"
+	"github.com/maddyonline/g2/auth"
+	"github.com/maddyonline/g2/cui"
+	"github.com/maddyonline/g2/frontend"
+	"github.com/maddyonline/g2/logging"
+	"github.com/maddyonline/g2/store"
+	"github.com/maddyonline/g2/webhook"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+var db *store.Store
+
+// ticketRecord is what gets persisted for every ticket: the session and
+// the candidate's tasks.
+type ticketRecord struct {
+	Session *cui.Session
+	Tasks   []*cui.Task
+}
+
+// sessionTasks returns copies of the tasks of a ticket in order, which can
+// be read without holding stateLock.
+func sessionTasks(session *cui.Session) []*cui.Task {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+	list := []*cui.Task{}
+	for _, id := range session.Ticket.Options.TaskNames {
+		if task, ok := tasks[cui.TaskKey{session.Ticket.Id, id}]; ok {
+			saved := *task
+			list = append(list, &saved)
+		}
+	}
+	return list
+}
+
+func saveSession(session *cui.Session) {
+	rec := &ticketRecord{Session: session, Tasks: sessionTasks(session)}
+	session.Lock()
+	err := db.Put("tickets", session.Ticket.Id, rec)
+	session.Unlock()
+	if err != nil {
+		log.Errorj(logging.Line("Saving ticket failed", logging.TICKET_ID, session.Ticket.Id, "error", err.Error()))
+	}
+}
+
+func loadSessions() error {
+	ids, err := db.List("tickets")
+	if err != nil {
+		return err
+	}
+	stateLock.Lock()
+	defer stateLock.Unlock()
+	for _, id := range ids {
+		rec := &ticketRecord{}
+		if err := db.Get("tickets", id, rec); err != nil {
+			return err
+		}
+		cuiSessions[id] = rec.Session
+		for _, task := range rec.Tasks {
+			tasks[cui.TaskKey{id, task.Id}] = task
+		}
+	}
+	log.Infoj(logging.Line("Loaded tickets", "tickets", len(ids)))
+	return nil
+}
+
+type ticketRequest struct {
+	Candidate      string   `json:"candidate"`
+	Problems       []string `json:"problems"`
+	ProgLangs      []string `json:"prg_langs"`
+	TimeLimit      int      `json:"time_limit"`
+	LanguageServer bool     `json:"language_server"`
+}
+
+type ticketSummary struct {
+	Id             string           `json:"id"`
+	Url            string           `json:"url"`
+	Candidate      string           `json:"candidate"`
+	Problems       []string         `json:"problems"`
+	ProgLangs      []string         `json:"prg_langs"`
+	TimeLimit      int              `json:"time_limit"`
+	LanguageServer bool             `json:"language_server"`
+	Status         cui.TicketStatus `json:"status"`
+	Shared         bool             `json:"shared"`
+	Created        time.Time        `json:"created"`
+	StartTime      *time.Time       `json:"start_time,omitempty"`
+	EndTime        *time.Time       `json:"end_time,omitempty"`
+}
+
+type taskDetail struct {
+	Id              string `json:"id"`
+	ProblemVersion  string `json:"problem_version,omitempty"`
+	Status          string `json:"status"`
+	ProgLang        string `json:"prg_lang"`
+	CurrentSolution string `json:"current_solution"`
+}
+
+type ticketDetail struct {
+	ticketSummary
+	Tasks       []*taskDetail     `json:"tasks"`
+	Submissions []*cui.Submission `json:"submissions"`
+	Events      []*cui.Event      `json:"events"`
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func summarize(session *cui.Session) *ticketSummary {
+	session.Refresh()
+	shared := session.Shared()
+	session.Lock()
+	defer session.Unlock()
+	opts := session.Ticket.Options
+	langs := []string{}
+	for name := range opts.ProgLangList {
+		langs = append(langs, name)
+	}
+	sort.Strings(langs)
+	return &ticketSummary{
+		Id:             session.Ticket.Id,
+		Url:            fmt.Sprintf("/cui/%s", session.Ticket.Id),
+		Candidate:      session.Candidate,
+		Problems:       opts.TaskNames,
+		ProgLangs:      langs,
+		TimeLimit:      session.TimeLimit,
+		LanguageServer: opts.LanguageServer,
+		Status:         session.Status,
+		Shared:         shared,
+		Created:        session.Created,
+		StartTime:      optionalTime(session.StartTime),
+		EndTime:        optionalTime(session.EndTime),
+	}
+}
+
+func detail(session *cui.Session) *ticketDetail {
+	d := &ticketDetail{ticketSummary: *summarize(session)}
+	for _, task := range sessionTasks(session) {
+		d.Tasks = append(d.Tasks, &taskDetail{task.Id, task.ProblemVersion, task.Status, task.ProgLang, task.CurrentSolution})
+	}
+	d.Submissions = session.History()
+	d.Events = session.EventLog()
+	return d
+}
+
+// problemStatus tells whether a problem loaded. A problem with an error
+// may still be served in its last good version, in which case it has a
+// title.
+type problemStatus struct {
+	Name    string `json:"name"`
+	Title   string `json:"title,omitempty"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func problemStatuses() []*problemStatus {
+	probs, versions, errs := problemSet.Problems(), problemSet.Versions(), problemSet.Errors()
+	list := []*problemStatus{}
+	for _, name := range problemSet.Names() {
+		st := &problemStatus{Name: name}
+		if p, ok := probs[name]; ok {
+			st.Title = p.Title
+			st.Version = versions[name]
+		}
+		if err, ok := errs[name]; ok {
+			st.Error = err.Error()
+		}
+		list = append(list, st)
+	}
+	return list
+}
+
+type loginRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Label    string `json:"label"`
+}
+
+type userRequest struct {
+	Name     string    `json:"name"`
+	Password string    `json:"password"`
+	Role     auth.Role `json:"role"`
+}
+
+// bootstrapAdmin creates the "admin" account on first start so that the
+// API can be used at all.
+func bootstrapAdmin(a *auth.Auth, password string) error {
+	ok, err := a.HasUsers()
+	if err != nil || ok {
+		return err
+	}
+	if password == "" {
+		log.Warnj(logging.Line("No users exist yet; start with -admin-password to create the admin account"))
+		return nil
+	}
+	_, err = a.AddUser("admin", password, auth.ADMIN)
+	if err == nil {
+		log.Infoj(logging.Line("Created admin account", "user", "admin"))
+	}
+	return err
+}
+
+func addAdminHandlers(e *echo.Echo, a *auth.Auth, tmpl *template.Template) {
+	e.Post("/api/v1/login", func(c echo.Context) error {
+		req := &loginRequest{}
+		if err := c.Bind(req); err != nil {
+			return err
+		}
+		user, err := a.Login(req.Name, req.Password)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		}
+		token, err := a.NewToken(user, req.Label)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, map[string]string{"token": token, "role": string(user.Role)})
+	})
+
+	api := e.Group("/api/v1", a.Middleware())
+	anyone := auth.Require(auth.RECRUITER, auth.REVIEWER)
+	recruiters := auth.Require(auth.RECRUITER)
+	admins := auth.Require(auth.ADMIN)
+
+	api.Post("/logout", func(c echo.Context) error {
+		token := strings.TrimPrefix(c.Request().Header().Get(echo.HeaderAuthorization), "Bearer ")
+		a.RevokeToken(token)
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	api.Get("/users", func(c echo.Context) error {
+		users, err := a.Users()
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			user.PasswordHash = nil
+		}
+		return c.JSON(http.StatusOK, users)
+	}, admins)
+
+	api.Post("/users", func(c echo.Context) error {
+		req := &userRequest{}
+		if err := c.Bind(req); err != nil {
+			return err
+		}
+		user, err := a.AddUser(req.Name, req.Password, req.Role)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		user.PasswordHash = nil
+		log.Infoj(requestLine(c, "Added user", "user", auth.CurrentUser(c).Name, "new_user", user.Name, "role", string(user.Role)))
+		return c.JSON(http.StatusCreated, user)
+	}, admins)
+
+	api.Post("/tickets", func(c echo.Context) error {
+		req := &ticketRequest{}
+		if err := c.Bind(req); err != nil {
+			return err
+		}
+		if req.Candidate == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "candidate is required")
+		}
+		if req.TimeLimit <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "time_limit must be positive")
+		}
+		stateLock.Lock()
+		ticket, err := cli.NewTicket(tasks, req.Problems, req.ProgLangs)
+		if err != nil {
+			stateLock.Unlock()
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		ticket.Options.LanguageServer = req.LanguageServer
+		session := cui.NewSession(ticket, req.Candidate, req.TimeLimit)
+		cuiSessions[ticket.Id] = session
+		stateLock.Unlock()
+		saveSession(session)
+		ticketsCreated.Inc()
+		notify(webhook.TICKET_CREATED, session)
+		log.Infoj(requestLine(c, "Created ticket", logging.TICKET_ID, ticket.Id, "candidate", req.Candidate))
+		return c.JSON(http.StatusCreated, detail(session))
+	}, recruiters)
+
+	api.Get("/tickets", func(c echo.Context) error {
+		status := cui.TicketStatus(c.QueryParam("status"))
+		stateLock.Lock()
+		sessions := []*cui.Session{}
+		for _, session := range cuiSessions {
+			sessions = append(sessions, session)
+		}
+		stateLock.Unlock()
+		list := []*ticketSummary{}
+		for _, session := range sessions {
+			summary := summarize(session)
+			if status == "" || summary.Status == status {
+				list = append(list, summary)
+			}
+		}
+		sort.Sort(byCreated(list))
+		return c.JSON(http.StatusOK, list)
+	}, anyone)
+
+	api.Get("/tickets/:ticket_id", func(c echo.Context) error {
+		session, ok := getSession(c.Param("ticket_id"))
+		if !ok {
+			return echo.NewHTTPError(http.StatusNotFound, "No such ticket")
+		}
+		return c.JSON(http.StatusOK, detail(session))
+	}, anyone)
+
+	api.Get("/tickets/:ticket_id/report", func(c echo.Context) error {
+		session, ok := getSession(c.Param("ticket_id"))
+		if !ok {
+			return echo.NewHTTPError(http.StatusNotFound, "No such ticket")
+		}
+		report := frontend.NewReport(frontend.NewReview(session, sessionTasks(session)), sessionTasks(session), closedReviews())
+		b, err := frontend.RenderReport(tmpl, report)
+		if err != nil {
+			return err
+		}
+		c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="report-%s.html"`, session.Ticket.Id))
+		return c.HTML(http.StatusOK, string(b))
+	}, anyone)
+
+	api.Post("/tickets/:ticket_id/cancel", func(c echo.Context) error {
+		session, ok := getSession(c.Param("ticket_id"))
+		if !ok {
+			return echo.NewHTTPError(http.StatusNotFound, "No such ticket")
+		}
+		if !session.Close(cui.CANCELLED) {
+			return echo.NewHTTPError(http.StatusConflict, "Ticket is already closed")
+		}
+		saveSession(session)
+		closeLanguageServer(session.Ticket.Id)
+		log.Infoj(requestLine(c, "Cancelled ticket", "user", auth.CurrentUser(c).Name))
+		return c.JSON(http.StatusOK, detail(session))
+	}, recruiters)
+
+	// unbind lets a candidate who lost the cookie of their browser open the
+	// ticket in another one.
+	api.Post("/tickets/:ticket_id/unbind", func(c echo.Context) error {
+		session, ok := getSession(c.Param("ticket_id"))
+		if !ok {
+			return echo.NewHTTPError(http.StatusNotFound, "No such ticket")
+		}
+		if session.Refresh().Closed() {
+			return echo.NewHTTPError(http.StatusConflict, "Ticket is closed")
+		}
+		if !session.Unbind(auth.CurrentUser(c).Name) {
+			return echo.NewHTTPError(http.StatusConflict, "Ticket is not bound to a browser")
+		}
+		saveSession(session)
+		log.Warnj(requestLine(c, "Unbound ticket", "user", auth.CurrentUser(c).Name))
+		return c.JSON(http.StatusOK, detail(session))
+	}, recruiters)
+
+	api.Get("/cache", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, resultCache.Stats())
+	}, admins)
+
+	api.Get("/problems", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, problemStatuses())
+	}, anyone)
+
+	addInviteAdminHandlers(api)
+	addExportHandlers(api, anyone)
+	addWebhookAdminHandlers(api, admins)
+}
+
+// byCreated orders tickets newest first.
+type byCreated []*ticketSummary
+
+func (a byCreated) Len() int           { return len(a) }
+func (a byCreated) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byCreated) Less(i, j int) bool { return a[i].Created.After(a[j].Created) }
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/maddyonline/g2/store"
+	"golang.org/x/crypto/bcrypt"
+	"time"
+)
+
+type Role string
+
+const (
+	ADMIN     Role = "admin"
+	RECRUITER Role = "recruiter"
+	REVIEWER  Role = "reviewer"
+)
+
+func (r Role) Valid() bool {
+	return r == ADMIN || r == RECRUITER || r == REVIEWER
+}
+
+var ErrBadCredentials = errors.New("Invalid credentials")
+
+type User struct {
+	Name         string    `json:"name"`
+	Role         Role      `json:"role"`
+	PasswordHash []byte    `json:"password_hash,omitempty"`
+	Created      time.Time `json:"created"`
+}
+
+// Token is an API token as stored; only the SHA-256 of the token itself is
+// kept on disk.
+type Token struct {
+	User    string    `json:"user"`
+	Label   string    `json:"label"`
+	Created time.Time `json:"created"`
+}
+
+// Auth manages local accounts and API tokens.
+type Auth struct {
+	Store *store.Store
+}
+
+func New(db *store.Store) *Auth {
+	return &Auth{Store: db}
+}
+
+func (a *Auth) HasUsers() (bool, error) {
+	names, err := a.Store.List("users")
+	return len(names) > 0, err
+}
+
+func (a *Auth) AddUser(name, password string, role Role) (*User, error) {
+	if !role.Valid() {
+		return nil, fmt.Errorf("Unknown role: %q", role)
+	}
+	if name == "" || password == "" {
+		return nil, errors.New("Name and password are required")
+	}
+	if _, err := a.GetUser(name); err == nil {
+		return nil, fmt.Errorf("User %q already exists", name)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	user := &User{Name: name, Role: role, PasswordHash: hash, Created: time.Now()}
+	if err := a.Store.Put("users", name, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (a *Auth) GetUser(name string) (*User, error) {
+	user := &User{}
+	if err := a.Store.Get("users", name, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (a *Auth) Users() ([]*User, error) {
+	names, err := a.Store.List("users")
+	if err != nil {
+		return nil, err
+	}
+	users := []*User{}
+	for _, name := range names {
+		user, err := a.GetUser(name)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// Login checks a local account's password.
+func (a *Auth) Login(name, password string) (*User, error) {
+	user, err := a.GetUser(name)
+	if err != nil {
+		return nil, ErrBadCredentials
+	}
+	if bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(password)) != nil {
+		return nil, ErrBadCredentials
+	}
+	return user, nil
+}
+
+func tokenId(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewToken issues a fresh API token for user. The token is only ever
+// returned here.
+func (a *Auth) NewToken(user *User, label string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	t := &Token{User: user.Name, Label: label, Created: time.Now()}
+	if err := a.Store.Put("tokens", tokenId(token), t); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (a *Auth) RevokeToken(token string) error {
+	return a.Store.Delete("tokens", tokenId(token))
+}
+
+func (a *Auth) UserForToken(token string) (*User, error) {
+	t := &Token{}
+	if err := a.Store.Get("tokens", tokenId(token), t); err != nil {
+		return nil, ErrBadCredentials
+	}
+	user, err := a.GetUser(t.User)
+	if err != nil {
+		return nil, ErrBadCredentials
+	}
+	return user, nil
+}
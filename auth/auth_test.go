@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"encoding/base64"
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/test"
+	"github.com/maddyonline/g2/store"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+)
+
+func newAuth(t *testing.T) (*Auth, func()) {
+	dir, err := ioutil.TempDir("", "g2-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(db), func() { os.RemoveAll(dir) }
+}
+
+func TestLoginAndTokens(t *testing.T) {
+	a, cleanup := newAuth(t)
+	defer cleanup()
+
+	if _, err := a.AddUser("alice", "secret", RECRUITER); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.AddUser("alice", "other", REVIEWER); err == nil {
+		t.Errorf("AddUser: expected duplicate user to be rejected")
+	}
+	if _, err := a.AddUser("bob", "secret", Role("boss")); err == nil {
+		t.Errorf("AddUser: expected unknown role to be rejected")
+	}
+	if _, err := a.Login("alice", "wrong"); err != ErrBadCredentials {
+		t.Errorf("Login with wrong password: got %v", err)
+	}
+	user, err := a.Login("alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := a.NewToken(user, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := a.UserForToken(token); err != nil || got.Name != "alice" {
+		t.Errorf("UserForToken: got %v, %v", got, err)
+	}
+	if err := a.RevokeToken(token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.UserForToken(token); err != ErrBadCredentials {
+		t.Errorf("UserForToken after revoke: got %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	a, cleanup := newAuth(t)
+	defer cleanup()
+	admin, _ := a.AddUser("root", "pw", ADMIN)
+	reviewer, _ := a.AddUser("rita", "pw", REVIEWER)
+	adminToken, _ := a.NewToken(admin, "")
+	reviewerToken, _ := a.NewToken(reviewer, "")
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("rita:pw"))
+
+	e := echo.New()
+	ok := func(c echo.Context) error { return c.String(http.StatusOK, CurrentUser(c).Name) }
+	h := a.Middleware()(Require(RECRUITER)(ok))
+
+	for _, tc := range []struct {
+		header string
+		code   int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer nope", http.StatusUnauthorized},
+		{"Bearer " + reviewerToken, http.StatusForbidden},
+		{basic, http.StatusForbidden},
+		{"Bearer " + adminToken, http.StatusOK},
+	} {
+		req := test.NewRequest(echo.GET, "/api/v1/tickets", nil)
+		if tc.header != "" {
+			req.Header().Set(echo.HeaderAuthorization, tc.header)
+		}
+		rec := test.NewResponseRecorder()
+		c := e.NewContext(req, rec)
+		code := http.StatusOK
+		if err := h(c); err != nil {
+			code = err.(*echo.HTTPError).Code
+		}
+		if code != tc.code {
+			t.Errorf("Authorization %q: got %d, want %d", tc.header, code, tc.code)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"encoding/base64"
+	"github.com/labstack/echo"
+	"net/http"
+	"strings"
+)
+
+const userKey = "auth.user"
+
+// CurrentUser returns the user authenticated by Middleware, or nil.
+func CurrentUser(c echo.Context) *User {
+	user, _ := c.Get(userKey).(*User)
+	return user
+}
+
+// credentials authenticates the request from either an API token
+// ("Authorization: Bearer <token>") or a local account
+// ("Authorization: Basic ...").
+func (a *Auth) credentials(c echo.Context) (*User, error) {
+	header := c.Request().Header().Get(echo.HeaderAuthorization)
+	switch {
+	case strings.HasPrefix(header, "Bearer "):
+		return a.UserForToken(strings.TrimPrefix(header, "Bearer "))
+	case strings.HasPrefix(header, "Basic "):
+		b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "Basic "))
+		if err != nil {
+			return nil, ErrBadCredentials
+		}
+		cred := strings.SplitN(string(b), ":", 2)
+		if len(cred) != 2 {
+			return nil, ErrBadCredentials
+		}
+		return a.Login(cred[0], cred[1])
+	}
+	return nil, ErrBadCredentials
+}
+
+// Middleware rejects unauthenticated requests and makes the user available
+// through CurrentUser.
+func (a *Auth) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user, err := a.credentials(c)
+			if err != nil {
+				// Lets browsers prompt for a local account.
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="g2"`)
+				return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+			}
+			c.Set(userKey, user)
+			return next(c)
+		}
+	}
+}
+
+// Require only lets through users having one of the given roles. Admins are
+// always allowed.
+func Require(roles ...Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user := CurrentUser(c)
+			if user == nil {
+				return echo.NewHTTPError(http.StatusUnauthorized)
+			}
+			if user.Role == ADMIN {
+				return next(c)
+			}
+			for _, role := range roles {
+				if user.Role == role {
+					return next(c)
+				}
+			}
+			return echo.NewHTTPError(http.StatusForbidden)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+// Package authoring scaffolds, validates and checks problems.
+//
+// A problem is a directory laid out as follows:
+//
+//	<name>/problem.md         statement; templates are the ```cpp and ```python
+//	                          code blocks under "## Templates"
+//	<name>/problem.<lang>.md  statement translated to the human language lang
+//	<name>/meta.json          tags, difficulty and languages (see catalog)
+//	<name>/tests/<n>.in       test input, with the expected output in <n>.out;
+//	                          example<n> tests are also shown in the statement
+//	<name>/solutions/*        reference solutions, which must pass every test
+//	<name>/solutions/wrong/*  wrong solutions, which must fail
+package authoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/maddyonline/g2/catalog"
+	"github.com/maddyonline/g2/problemset"
+	"github.com/maddyonline/umpire"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const (
+	Statement    = "problem.md"
+	TestsDir     = "tests"
+	SolutionsDir = "solutions"
+	WrongDir     = "wrong"
+)
+
+// Languages maps the extension of solution files to the language the
+// executor knows them by, and FileNames gives the file name it expects.
+var (
+	Languages = map[string]string{".cpp": "cpp", ".py": "python"}
+	FileNames = map[string]string{"cpp": "main.cpp", "python": "main.py"}
+)
+
+type Options struct {
+	Title string
+	// ProgLangs are the languages to write templates for, as named by the
+	// executor ("cpp", "python").
+	ProgLangs []string
+}
+
+var statementTmpl = template.Must(template.New(Statement).Parse(`# {{.Title}}
+
+Add two integers.
+
+Given two integers a and b on one line, print a + b.
+
+#### Input
+
+	1 2
+
+#### Output
+
+	3
+
+## Templates
+{{range .Templates}}
+` + "```" + `{{.Lang}}
+{{.Code}}` + "```" + `
+{{end}}`))
+
+var templates = map[string]string{
+	"cpp": `#include <iostream>
+using namespace std;
+
+int main() {
+  // Read from stdin, write to stdout.
+  return 0;
+}
+`,
+	"python": `import sys
+
+# Read from stdin, write to stdout.
+`,
+}
+
+// The scaffolded problem passes check as is, so authors start from a
+// working example.
+var scaffold = map[string]string{
+	filepath.Join(TestsDir, "1.in"):  "1 2\n",
+	filepath.Join(TestsDir, "1.out"): "3\n",
+	filepath.Join(TestsDir, "2.in"):  "-5 12\n",
+	filepath.Join(TestsDir, "2.out"): "7\n",
+	filepath.Join(SolutionsDir, "main.cpp"): `#include <iostream>
+using namespace std;
+
+int main() {
+  long long a, b;
+  cin >> a >> b;
+  cout << a + b << endl;
+  return 0;
+}
+`,
+	filepath.Join(SolutionsDir, "main.py"): `a, b = map(int, input().split())
+print(a + b)
+`,
+	filepath.Join(SolutionsDir, WrongDir, "main.cpp"): `#include <iostream>
+using namespace std;
+
+int main() {
+  int a, b;
+  cin >> a >> b;
+  cout << a - b << endl;
+  return 0;
+}
+`,
+}
+
+// New creates the problem name in dir.
+func New(dir, name string, opts Options) error {
+	root := filepath.Join(dir, name)
+	if _, err := os.Stat(root); err == nil {
+		return fmt.Errorf("%s already exists", root)
+	}
+	if opts.Title == "" {
+		opts.Title = name
+	}
+	if len(opts.ProgLangs) == 0 {
+		opts.ProgLangs = []string{"cpp", "python"}
+	}
+	type tmpl struct{ Lang, Code string }
+	data := struct {
+		Title     string
+		Templates []tmpl
+	}{Title: opts.Title}
+	for _, lang := range opts.ProgLangs {
+		code, ok := templates[lang]
+		if !ok {
+			return fmt.Errorf("No template for language %q", lang)
+		}
+		data.Templates = append(data.Templates, tmpl{lang, code})
+	}
+
+	for _, d := range []string{TestsDir, filepath.Join(SolutionsDir, WrongDir)} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0755); err != nil {
+			return err
+		}
+	}
+	f, err := os.Create(filepath.Join(root, Statement))
+	if err != nil {
+		return err
+	}
+	if err := statementTmpl.Execute(f, data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	meta, err := json.MarshalIndent(&catalog.Meta{Tags: []string{}, Difficulty: catalog.EASY}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, catalog.MetaFile), append(meta, '\n'), 0644); err != nil {
+		return err
+	}
+	for path, content := range scaffold {
+		lang := Languages[filepath.Ext(path)]
+		if lang != "" && !contains(opts.ProgLangs, lang) {
+			continue
+		}
+		if err := ioutil.WriteFile(filepath.Join(root, path), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Solution is a file of the solutions directory.
+type Solution struct {
+	Path      string
+	Lang      string
+	WantsPass bool
+}
+
+// Solutions lists the reference solutions, then the wrong ones.
+func Solutions(dir, name string) ([]*Solution, error) {
+	list := []*Solution{}
+	for _, d := range []struct {
+		path string
+		pass bool
+	}{
+		{filepath.Join(dir, name, SolutionsDir), true},
+		{filepath.Join(dir, name, SolutionsDir, WrongDir), false},
+	} {
+		infos, err := ioutil.ReadDir(d.path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range infos {
+			lang, ok := Languages[filepath.Ext(fi.Name())]
+			if fi.IsDir() || !ok {
+				continue
+			}
+			list = append(list, &Solution{filepath.Join(d.path, fi.Name()), lang, d.pass})
+		}
+	}
+	return list, nil
+}
+
+// Validate checks the structure of a problem and that load accepts it.
+// It returns everything that is wrong.
+func Validate(dir, name string, load problemset.LoadFunc) []error {
+	root := filepath.Join(dir, name)
+	if fi, err := os.Stat(root); err != nil || !fi.IsDir() {
+		return []error{fmt.Errorf("%s is not a directory", root)}
+	}
+	errs := []error{}
+
+	tmp, err := ioutil.TempDir("", "g2-validate")
+	if err != nil {
+		return append(errs, err)
+	}
+	defer os.RemoveAll(tmp)
+	if err := problemset.CopyDir(root, filepath.Join(tmp, name)); err != nil {
+		return append(errs, err)
+	}
+	list, err := load(tmp)
+	if p, ok := list[name]; err != nil || !ok {
+		if err == nil {
+			err = problemset.ErrNotAProblem{name}
+		}
+		errs = append(errs, fmt.Errorf("%s: %v", Statement, err))
+	} else {
+		if strings.TrimSpace(p.Title) == "" {
+			errs = append(errs, fmt.Errorf("%s: no title", Statement))
+		}
+		if strings.TrimSpace(p.FullDesc) == "" {
+			errs = append(errs, fmt.Errorf("%s: no statement", Statement))
+		}
+		if len(p.Templates) == 0 {
+			errs = append(errs, fmt.Errorf("%s: no templates", Statement))
+		}
+	}
+
+	if _, err := catalog.LoadMeta(dir, name); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, validateTests(filepath.Join(root, TestsDir))...)
+
+	solutions, err := Solutions(dir, name)
+	if err != nil {
+		return append(errs, err)
+	}
+	reference := false
+	for _, s := range solutions {
+		reference = reference || s.WantsPass
+	}
+	if !reference {
+		errs = append(errs, fmt.Errorf("%s: no reference solution", SolutionsDir))
+	}
+	return errs
+}
+
+// validateTests checks there are tests and each input has an output.
+func validateTests(dir string) []error {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return []error{err}
+	}
+	ins, outs := map[string]bool{}, map[string]bool{}
+	for _, fi := range infos {
+		ext := filepath.Ext(fi.Name())
+		base := strings.TrimSuffix(fi.Name(), ext)
+		switch ext {
+		case ".in":
+			ins[base] = true
+		case ".out":
+			outs[base] = true
+		}
+	}
+	errs := []error{}
+	if len(ins) == 0 {
+		errs = append(errs, fmt.Errorf("%s: no tests", TestsDir))
+	}
+	names := []string{}
+	for base := range ins {
+		names = append(names, base)
+	}
+	for base := range outs {
+		if !ins[base] {
+			names = append(names, base)
+		}
+	}
+	sort.Strings(names)
+	for _, base := range names {
+		switch {
+		case !outs[base]:
+			errs = append(errs, fmt.Errorf("%s: %s.in has no %s.out", TestsDir, base, base))
+		case !ins[base]:
+			errs = append(errs, fmt.Errorf("%s: %s.out has no %s.in", TestsDir, base, base))
+		}
+	}
+	return errs
+}
+
+// ErrJudge is a status of the judge that is not a verdict, such as a
+// container that failed to start.
+type ErrJudge struct {
+	Path    string
+	Status  umpire.StatusT
+	Details string
+}
+
+func (e ErrJudge) Error() string {
+	return fmt.Sprintf("%s: judge status %q: %s", e.Path, e.Status, e.Details)
+}
+
+// judge is umpire.JudgeDefault, replaced in tests.
+var judge = umpire.JudgeDefault
+
+// Result is how a solution fared when judged.
+type Result struct {
+	*Solution
+	Passed bool
+	Output string
+}
+
+// OK reports whether the solution did what it is meant to.
+func (r *Result) OK() bool {
+	return r.Passed == r.WantsPass
+}
+
+// Check judges every solution of the problem with agent, whose problems
+// directory must be dir, the same way the server judges candidates. A
+// status of the judge that is not a verdict is an ErrJudge.
+func Check(agent *umpire.Agent, dir, name string) ([]*Result, error) {
+	solutions, err := Solutions(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	results := []*Result{}
+	for _, s := range solutions {
+		code, err := ioutil.ReadFile(s.Path)
+		if err != nil {
+			return nil, err
+		}
+		payload := &umpire.Payload{
+			Problem:  &umpire.Problem{name},
+			Language: s.Lang,
+			Files: []*umpire.InMemoryFile{
+				&umpire.InMemoryFile{
+					Name:    FileNames[s.Lang],
+					Content: string(code),
+				},
+			},
+		}
+		out := judge(agent, payload)
+		if out.Status != umpire.Pass && out.Status != umpire.Fail {
+			return nil, ErrJudge{s.Path, out.Status, out.Details}
+		}
+		results = append(results, &Result{s, out.Status == umpire.Pass, out.Stdout + "\n" + out.Stderr + "\n" + out.Details})
+	}
+	return results, nil
+}
@@ -0,0 +1,117 @@
+package authoring
+
+import (
+	"github.com/maddyonline/problems"
+	"github.com/maddyonline/umpire"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var templateRe = regexp.MustCompile("(?s)```(\\w+)\n(.*?)```")
+
+// fakeLoad stands in for problems.GetList, reading the title and the
+// templates from problem.md.
+func fakeLoad(dir string) (map[string]*problems.Problem, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	list := map[string]*problems.Problem{}
+	for _, fi := range infos {
+		b, err := ioutil.ReadFile(filepath.Join(dir, fi.Name(), Statement))
+		if err != nil {
+			continue
+		}
+		desc := string(b)
+		p := &problems.Problem{Name: fi.Name(), FullDesc: desc, Templates: map[string]string{}}
+		p.Title = strings.TrimPrefix(strings.SplitN(desc, "\n", 2)[0], "# ")
+		for _, m := range templateRe.FindAllStringSubmatch(desc, -1) {
+			p.Templates[m[1]] = m[2]
+		}
+		list[fi.Name()] = p
+	}
+	return list, nil
+}
+
+func TestNewValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "g2-authoring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := New(dir, "sum", Options{Title: "Sum", ProgLangs: []string{"python"}}); err != nil {
+		t.Fatal(err)
+	}
+	if errs := Validate(dir, "sum", fakeLoad); len(errs) != 0 {
+		t.Fatalf("a new problem is invalid: %v", errs)
+	}
+	list, _ := fakeLoad(dir)
+	if p := list["sum"]; p.Title != "Sum" || len(p.Templates) != 1 || !strings.Contains(p.Templates["python"], "import sys") {
+		t.Errorf("got %+v", p)
+	}
+	solutions, err := Solutions(dir, "sum")
+	if err != nil || len(solutions) != 1 || solutions[0].Lang != "python" || !solutions[0].WantsPass {
+		t.Errorf("solutions: %v, %v", solutions, err)
+	}
+	if err := New(dir, "sum", Options{}); err == nil {
+		t.Error("New overwrote a problem")
+	}
+
+	os.Remove(filepath.Join(dir, "sum", TestsDir, "2.out"))
+	os.Remove(filepath.Join(dir, "sum", SolutionsDir, "main.py"))
+	ioutil.WriteFile(filepath.Join(dir, "sum", "meta.json"), []byte(`{"difficulty": "trivial"}`), 0644)
+	errs := Validate(dir, "sum", fakeLoad)
+	if len(errs) != 3 {
+		t.Errorf("got %d errors: %v", len(errs), errs)
+	}
+	if errs := Validate(dir, "missing", fakeLoad); len(errs) != 1 {
+		t.Errorf("missing problem: %v", errs)
+	}
+}
+
+func TestNewUnknownLanguage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "g2-authoring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := New(dir, "sum", Options{ProgLangs: []string{"cobol"}}); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "g2-authoring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := New(dir, "sum", Options{Title: "Sum", ProgLangs: []string{"python"}}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { judge = umpire.JudgeDefault }()
+	status := umpire.Pass
+	judge = func(agent *umpire.Agent, payload *umpire.Payload) *umpire.Response {
+		return &umpire.Response{Status: status, Details: "details"}
+	}
+
+	results, err := Check(nil, dir, "sum")
+	if err != nil || len(results) != 1 || !results[0].Passed || !results[0].OK() {
+		t.Errorf("Pass: %v, %v", results, err)
+	}
+	status = umpire.Fail
+	if results, err := Check(nil, dir, "sum"); err != nil || len(results) != 1 || results[0].Passed {
+		t.Errorf("Fail: %v, %v", results, err)
+	}
+	// A broken executor does not pass the reference solution.
+	status = umpire.StatusT("error")
+	results, err = Check(nil, dir, "sum")
+	if e, ok := err.(ErrJudge); !ok || e.Status != status || results != nil {
+		t.Errorf("error status: %v, %v", results, err)
+	}
+}
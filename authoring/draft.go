@@ -0,0 +1,344 @@
+package authoring
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/maddyonline/g2/catalog"
+	"github.com/maddyonline/g2/problemset"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ExamplesHeading  = "## Examples"
+	TemplatesHeading = "## Templates"
+	// ExamplePrefix starts the names of the tests shown in the statement.
+	ExamplePrefix = "example"
+)
+
+// Test is a test case.
+type Test struct {
+	Input  string `json:"input"`
+	Output string `json:"output"`
+}
+
+// Draft is a problem in the form the editor works on. Saving it rewrites
+// the statement, meta.json and the tests, and leaves the solutions alone.
+type Draft struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Templates are keyed by executor language ("cpp", "python").
+	Templates map[string]string `json:"templates"`
+	// Examples are shown in the statement; Tests are hidden.
+	Examples []*Test      `json:"examples"`
+	Tests    []*Test      `json:"tests"`
+	Meta     catalog.Meta `json:"meta"`
+}
+
+type ErrInvalidDraft struct {
+	Problems []string
+}
+
+func (e ErrInvalidDraft) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+// ValidName reports whether name can be the directory of a problem.
+func ValidName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("%q is not a valid problem name", name)
+	}
+	return nil
+}
+
+// NewDraft is the sample problem New starts from, with templates for
+// progLangs (all languages if empty).
+func NewDraft(name string, progLangs []string) (*Draft, error) {
+	if len(progLangs) == 0 {
+		progLangs = []string{"cpp", "python"}
+	}
+	d := &Draft{
+		Name:        name,
+		Title:       name,
+		Description: "Add two integers.\n\nGiven two integers a and b on one line, print a + b.",
+		Templates:   map[string]string{},
+		Examples:    []*Test{{"1 2\n", "3\n"}},
+		Tests:       []*Test{{"-5 12\n", "7\n"}},
+		Meta:        catalog.Meta{Tags: []string{}, Difficulty: catalog.EASY},
+	}
+	for _, lang := range progLangs {
+		code, ok := templates[lang]
+		if !ok {
+			return nil, fmt.Errorf("No template for language %q", lang)
+		}
+		d.Templates[lang] = code
+	}
+	return d, nil
+}
+
+// Check returns what keeps the draft from being saved.
+func (d *Draft) Check() error {
+	problems := []string{}
+	if err := ValidName(d.Name); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if strings.TrimSpace(d.Title) == "" || strings.ContainsAny(d.Title, "\r\n") {
+		problems = append(problems, "the title must be a single, non-empty line")
+	}
+	if len(d.Templates) == 0 {
+		problems = append(problems, "there must be a template")
+	}
+	for _, lang := range sortedKeys(d.Templates) {
+		if _, ok := FileNames[lang]; !ok {
+			problems = append(problems, fmt.Sprintf("unknown language %q", lang))
+		}
+		if strings.Contains(d.Templates[lang], "```") {
+			problems = append(problems, fmt.Sprintf("the %s template must not contain ```", lang))
+		}
+	}
+	if len(d.Examples)+len(d.Tests) == 0 {
+		problems = append(problems, "there must be a test")
+	}
+	switch d.Meta.Difficulty {
+	case "", catalog.EASY, catalog.MEDIUM, catalog.HARD:
+	default:
+		problems = append(problems, fmt.Sprintf("unknown difficulty %q", d.Meta.Difficulty))
+	}
+	if len(problems) > 0 {
+		return ErrInvalidDraft{problems}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// indent makes s a markdown code block.
+func indent(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\r\n"), "\n")
+	return "\t" + strings.Join(lines, "\n\t") + "\n"
+}
+
+// Statement is the problem as candidates read it: the title, the
+// description and the examples, in markdown.
+func (d *Draft) Statement() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\n%s\n", strings.TrimSpace(d.Title), strings.TrimSpace(d.Description))
+	if len(d.Examples) > 0 {
+		fmt.Fprintf(&b, "\n%s\n", ExamplesHeading)
+		for _, t := range d.Examples {
+			fmt.Fprintf(&b, "\n#### Input\n\n%s\n#### Output\n\n%s", indent(t.Input), indent(t.Output))
+		}
+	}
+	return b.String()
+}
+
+// markdown is the content of problem.md.
+func (d *Draft) markdown() string {
+	var b bytes.Buffer
+	b.WriteString(d.Statement())
+	fmt.Fprintf(&b, "\n%s\n", TemplatesHeading)
+	for _, lang := range sortedKeys(d.Templates) {
+		code := d.Templates[lang]
+		if !strings.HasSuffix(code, "\n") {
+			code += "\n"
+		}
+		fmt.Fprintf(&b, "\n```%s\n%s```\n", lang, code)
+	}
+	return b.String()
+}
+
+// write writes the draft into the problem directory root, replacing its
+// tests.
+func (d *Draft) write(root string) error {
+	if err := ioutil.WriteFile(filepath.Join(root, Statement), []byte(d.markdown()), 0644); err != nil {
+		return err
+	}
+	meta := d.Meta
+	if meta.Tags == nil {
+		meta.Tags = []string{}
+	}
+	b, err := json.MarshalIndent(&meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, catalog.MetaFile), append(b, '\n'), 0644); err != nil {
+		return err
+	}
+	tests := filepath.Join(root, TestsDir)
+	if err := os.RemoveAll(tests); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(tests, 0755); err != nil {
+		return err
+	}
+	for _, set := range []struct {
+		prefix string
+		tests  []*Test
+	}{{ExamplePrefix, d.Examples}, {"", d.Tests}} {
+		for i, t := range set.tests {
+			base := filepath.Join(tests, fmt.Sprintf("%s%d", set.prefix, i+1))
+			if err := ioutil.WriteFile(base+".in", []byte(t.Input), 0644); err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(base+".out", []byte(t.Output), 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Save writes the draft into dir. The problem is assembled next to its
+// directory and swapped in, so the reload logic never sees it half
+// written.
+func Save(dir string, d *Draft) error {
+	if err := d.Check(); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(dir, "."+d.Name)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	root := filepath.Join(dir, d.Name)
+	staged := filepath.Join(tmp, d.Name)
+	_, err = os.Stat(root)
+	exists := err == nil
+	if exists {
+		err = problemset.CopyDir(root, staged)
+	} else {
+		err = os.MkdirAll(filepath.Join(staged, SolutionsDir), 0755)
+	}
+	if err != nil {
+		return err
+	}
+	if err := d.write(staged); err != nil {
+		return err
+	}
+	if exists {
+		if err := os.Rename(root, filepath.Join(tmp, "previous")); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(staged, root); err != nil {
+		if exists {
+			os.Rename(filepath.Join(tmp, "previous"), root)
+		}
+		return err
+	}
+	return nil
+}
+
+var templateBlock = regexp.MustCompile("(?s)```(\\w+)\n(.*?)```")
+
+// LoadDraft reads the problem name of dir back into a draft.
+func LoadDraft(dir, name string) (*Draft, error) {
+	if err := ValidName(name); err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, name, Statement))
+	if err != nil {
+		return nil, err
+	}
+	d := &Draft{Name: name, Templates: map[string]string{}, Examples: []*Test{}, Tests: []*Test{}}
+
+	var desc []string
+	section := ""
+	lines := strings.Split(strings.Replace(string(b), "\r\n", "\n", -1), "\n")
+	for i, line := range lines {
+		switch {
+		case d.Title == "" && section == "" && strings.HasPrefix(line, "# "):
+			d.Title = strings.TrimSpace(line[2:])
+		case line == ExamplesHeading || line == TemplatesHeading:
+			section = line
+			if line == TemplatesHeading {
+				for _, m := range templateBlock.FindAllStringSubmatch(strings.Join(lines[i+1:], "\n"), -1) {
+					d.Templates[m[1]] = m[2]
+				}
+			}
+		case section == "":
+			desc = append(desc, line)
+		}
+	}
+	d.Description = strings.TrimSpace(strings.Join(desc, "\n"))
+
+	meta, err := catalog.LoadMeta(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	d.Meta = *meta
+	if d.Meta.Tags == nil {
+		d.Meta.Tags = []string{}
+	}
+
+	infos, err := ioutil.ReadDir(filepath.Join(dir, name, TestsDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	bases := []string{}
+	for _, fi := range infos {
+		if filepath.Ext(fi.Name()) == ".in" {
+			bases = append(bases, strings.TrimSuffix(fi.Name(), ".in"))
+		}
+	}
+	sort.Sort(byTestName(bases))
+	for _, base := range bases {
+		path := filepath.Join(dir, name, TestsDir, base)
+		in, err := ioutil.ReadFile(path + ".in")
+		if err != nil {
+			return nil, err
+		}
+		out, err := ioutil.ReadFile(path + ".out")
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		t := &Test{string(in), string(out)}
+		if strings.HasPrefix(base, ExamplePrefix) {
+			d.Examples = append(d.Examples, t)
+		} else {
+			d.Tests = append(d.Tests, t)
+		}
+	}
+	return d, nil
+}
+
+// byTestName orders test names by number, so 10 comes after 9.
+type byTestName []string
+
+func (a byTestName) Len() int      { return len(a) }
+func (a byTestName) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byTestName) Less(i, j int) bool {
+	pi, ni := splitNumber(a[i])
+	pj, nj := splitNumber(a[j])
+	if pi != pj {
+		return pi < pj
+	}
+	return ni < nj
+}
+
+// splitNumber splits "example12" into "example" and 12.
+func splitNumber(s string) (string, int) {
+	i := len(s)
+	for i > 0 && s[i-1] >= '0' && s[i-1] <= '9' {
+		i--
+	}
+	n, err := strconv.Atoi(s[i:])
+	if err != nil {
+		return s, -1
+	}
+	return s[:i], n
+}
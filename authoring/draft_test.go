@@ -0,0 +1,80 @@
+package authoring
+
+import (
+	"github.com/maddyonline/g2/catalog"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoadDraft(t *testing.T) {
+	dir, err := ioutil.TempDir("", "g2-draft")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := New(dir, "sum", Options{Title: "Sum"}); err != nil {
+		t.Fatal(err)
+	}
+	d, err := LoadDraft(dir, "sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := NewDraft("sum", nil)
+	want.Title = "Sum"
+	// The scaffold keeps its example in the description and among the tests.
+	if len(d.Examples) != 0 || !strings.Contains(d.Description, "#### Input\n\n\t1 2\n") {
+		t.Errorf("scaffold example: %+v, %q", d.Examples, d.Description)
+	}
+	want.Description, want.Examples = d.Description, d.Examples
+	want.Tests = append([]*Test{{"1 2\n", "3\n"}}, want.Tests...)
+	if !reflect.DeepEqual(d, want) {
+		t.Fatalf("got %+v, want %+v", d, want)
+	}
+
+	d.Title = "Sum of two numbers"
+	d.Description = "Print a + b.\n\n# Not a title"
+	d.Templates = map[string]string{"python": "print(0)"}
+	d.Examples = []*Test{{"1 1\n", "2\n"}, {"2 2\n", "4\n"}}
+	tests := []*Test{}
+	for i := 0; i < 10; i++ {
+		tests = append(tests, &Test{strings.Repeat("1 ", i+1), ""})
+	}
+	d.Tests = tests
+	d.Meta = catalog.Meta{Tags: []string{"math"}, Difficulty: catalog.MEDIUM}
+	if err := Save(dir, d); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadDraft(dir, "sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Templates["python"] += "\n"
+	if !reflect.DeepEqual(got, d) {
+		t.Errorf("got %+v, want %+v", got, d)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sum", SolutionsDir, "main.cpp")); err != nil {
+		t.Errorf("saving lost the solutions: %v", err)
+	}
+	if infos, _ := ioutil.ReadDir(dir); len(infos) != 1 {
+		t.Errorf("saving left %d entries in the problems directory", len(infos))
+	}
+	if s := d.Statement(); !strings.Contains(s, ExamplesHeading+"\n\n#### Input\n\n\t1 1\n\n#### Output\n\n\t2\n") {
+		t.Errorf("statement:\n%s", s)
+	}
+}
+
+func TestCheckDraft(t *testing.T) {
+	d := &Draft{Name: "../x", Title: "Two\nlines", Templates: map[string]string{"cobol": "```"}, Meta: catalog.Meta{Difficulty: "trivial"}}
+	err := d.Check()
+	e, ok := err.(ErrInvalidDraft)
+	if !ok || len(e.Problems) != 6 {
+		t.Errorf("got %v", err)
+	}
+	if err := Save(os.TempDir(), d); err == nil {
+		t.Error("saved an invalid draft")
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"github.com/labstack/echo"
+	"github.com/labstack/gommon/log"
+	"github.com/maddyonline/g2/cui"
+	"github.com/maddyonline/g2/guard"
+	"github.com/maddyonline/g2/logging"
+	"sync"
+	"time"
+)
+
+// sessionCookie binds a ticket to the browser it was started in.
+const sessionCookie = "g2_session"
+
+var signer *guard.Signer
+
+// requestTicket is the ticket id a CUI request is about.
+func requestTicket(c echo.Context) string {
+	if id := c.Param("ticket_id"); id != "" {
+		return id
+	}
+	return c.FormValue("ticket")
+}
+
+func clientOf(c echo.Context) (string, string) {
+	return c.Request().RealIP(), c.Request().UserAgent()
+}
+
+// bindSession ties session to the requesting browser if it is not bound to
+// one yet.
+func bindSession(c echo.Context, session *cui.Session) bool {
+	ip, userAgent := clientOf(c)
+	if !session.Bind(ip, userAgent) {
+		return false
+	}
+	maxAge := time.Duration(session.TimeLimit)*time.Second + time.Hour
+	signer.SetCookie(c, sessionCookie, session.Ticket.Id, maxAge)
+	saveSession(session)
+	return true
+}
+
+// REJECT_SAVE_INTERVAL is how often a ticket is saved for requests from
+// browsers already rejected; a second browser polling would write it on
+// every request otherwise.
+const REJECT_SAVE_INTERVAL = time.Minute
+
+var (
+	rejectLock  sync.Mutex
+	rejectSaved = map[string]time.Time{}
+)
+
+func rejectSession(c echo.Context, ticketId string) {
+	session, ok := getSession(ticketId)
+	if !ok {
+		return
+	}
+	ip, userAgent := clientOf(c)
+	ev, first := session.Reject(ip, userAgent)
+	rejectLock.Lock()
+	save := first || time.Since(rejectSaved[ticketId]) > REJECT_SAVE_INTERVAL
+	if save {
+		rejectSaved[ticketId] = time.Now()
+	}
+	rejectLock.Unlock()
+	if !save {
+		return
+	}
+	saveSession(session)
+	log.Warnj(requestLine(c, "Rejected request from another browser", logging.TICKET_ID, ticketId, "remote_ip", ip, "user_agent", userAgent, "count", ev.Count))
+}
+
+// guardMessage words the errors of the signer's middlewares in the
+// candidate's language.
+func guardMessage(c echo.Context, err error) string {
+	session, _ := getSession(requestTicket(c))
+	switch err.(type) {
+	case guard.ErrOtherBrowser:
+		return cui.T(humanLang(c, session), cui.MSG_OTHER_BROWSER)
+	case guard.ErrInvalidCSRF:
+		return cui.T(humanLang(c, session), cui.MSG_INVALID_CSRF)
+	}
+	return err.Error()
+}
+
+// seenSession logs when the bound browser shows up from another address or
+// user agent.
+func seenSession(c echo.Context, session *cui.Session) {
+	ip, userAgent := clientOf(c)
+	if ev := session.Seen(ip, userAgent); ev != nil {
+		saveSession(session)
+		log.Warnj(requestLine(c, "Candidate changed machine", logging.TICKET_ID, session.Ticket.Id, "event", string(ev.Kind), "remote_ip", ip, "user_agent", userAgent))
+	}
+}
+
+// trackSession runs seenSession for requests that passed the cookie check.
+func trackSession(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if session, ok := getSession(requestTicket(c)); ok {
+			seenSession(c, session)
+		}
+		return next(c)
+	}
+}
+
+// boundToBrowser guards the CUI protocol routes: they must come from the
+// bound browser, and POSTs must carry the page's CSRF token.
+func boundToBrowser() []echo.MiddlewareFunc {
+	return []echo.MiddlewareFunc{
+		signer.RequireCookie(sessionCookie, requestTicket, rejectSession),
+		signer.CSRF(requestTicket),
+		trackSession,
+	}
+}
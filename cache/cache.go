@@ -0,0 +1,118 @@
+// Package cache remembers compile and run results by the content they were
+// computed from, so candidates pressing Verify again on the same code get
+// their answer without another container being started.
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+const (
+	COMPILE = "compile"
+	RUN     = "run"
+	JUDGE   = "judge"
+)
+
+// Key is everything a result depends on. Source and Input are hashes.
+type Key struct {
+	Kind     string
+	Problem  string
+	Version  string
+	ProgLang string
+	Source   string
+	Input    string
+}
+
+func Hash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+	Bytes     int64  `json:"bytes"`
+	MaxBytes  int64  `json:"max_bytes"`
+}
+
+type entry struct {
+	key   Key
+	value interface{}
+	size  int64
+}
+
+// Cache is a least recently used cache bounded by the total size of its
+// values. A nil Cache caches nothing.
+type Cache struct {
+	maxBytes int64
+	ll       *list.List
+	items    map[Key]*list.Element
+	stats    Stats
+	sync.Mutex
+}
+
+func New(maxBytes int64) *Cache {
+	return &Cache{
+		maxBytes: maxBytes,
+		ll:       list.New(),
+		items:    map[Key]*list.Element{},
+		stats:    Stats{MaxBytes: maxBytes},
+	}
+}
+
+func (c *Cache) Get(key Key) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.Lock()
+	defer c.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.ll.MoveToFront(el)
+	return el.Value.(*entry).value, true
+}
+
+// Put stores value, which takes size bytes, evicting the least recently
+// used values to make room. Values larger than the cache are not stored.
+func (c *Cache) Put(key Key, value interface{}, size int64) {
+	if c == nil || size > c.maxBytes {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	c.items[key] = c.ll.PushFront(&entry{key, value, size})
+	c.stats.Bytes += size
+	for c.stats.Bytes > c.maxBytes {
+		c.remove(c.ll.Back())
+		c.stats.Evictions++
+	}
+	c.stats.Entries = c.ll.Len()
+}
+
+func (c *Cache) remove(el *list.Element) {
+	e := c.ll.Remove(el).(*entry)
+	delete(c.items, e.key)
+	c.stats.Bytes -= e.size
+	c.stats.Entries = c.ll.Len()
+}
+
+func (c *Cache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+	c.Lock()
+	defer c.Unlock()
+	return c.stats
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"testing"
+)
+
+func key(source string) Key {
+	return Key{RUN, "sum", "v1", "cpp", Hash(source), Hash("1 2")}
+}
+
+func TestLRU(t *testing.T) {
+	c := New(10)
+	c.Put(key("a"), "A", 4)
+	c.Put(key("b"), "B", 4)
+	if v, ok := c.Get(key("a")); !ok || v != "A" {
+		t.Fatalf("Get(a) = %v, %v", v, ok)
+	}
+	// b is now the least recently used.
+	c.Put(key("c"), "C", 4)
+	if _, ok := c.Get(key("b")); ok {
+		t.Error("b was not evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.Get(key(k)); !ok {
+			t.Errorf("%s was evicted", k)
+		}
+	}
+	c.Put(key("huge"), "H", 11)
+	if _, ok := c.Get(key("huge")); ok {
+		t.Error("stored a value larger than the cache")
+	}
+
+	want := Stats{Hits: 3, Misses: 2, Evictions: 1, Entries: 2, Bytes: 8, MaxBytes: 10}
+	if got := c.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	c := New(10)
+	c.Put(key("a"), "old", 6)
+	c.Put(key("a"), "new", 6)
+	if v, _ := c.Get(key("a")); v != "new" {
+		t.Errorf("got %v", v)
+	}
+	if s := c.Stats(); s.Entries != 1 || s.Bytes != 6 || s.Evictions != 0 {
+		t.Errorf("Stats() = %+v", s)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	c := New(100)
+	c.Put(key("a"), "A", 1)
+	other := key("a")
+	other.Version = "v2"
+	if _, ok := c.Get(other); ok {
+		t.Error("a result of another problem version was returned")
+	}
+
+	var none *Cache
+	none.Put(key("a"), "A", 1)
+	if _, ok := none.Get(key("a")); ok {
+		t.Error("a nil cache returned a value")
+	}
+}
@@ -0,0 +1,200 @@
+// Package candidate is a client of the candidate interface protocol, for
+// taking an assessment from a terminal or a script.
+package candidate
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/maddyonline/g2/cui"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const USER_AGENT = "g2-cli"
+
+// Poll is how often Run asks for the verdict of a solution still being
+// judged, and Timeout how long it waits for it.
+var (
+	Poll    = time.Second
+	Timeout = 5 * time.Minute
+)
+
+type ErrStatus struct {
+	Method string
+	Path   string
+	Code   int
+	Body   string
+}
+
+func (e ErrStatus) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.Path, e.Code, strings.TrimSpace(e.Body))
+}
+
+// State is what a later command needs to go on with a ticket.
+type State struct {
+	Server    string            `json:"server"`
+	Ticket    string            `json:"ticket"`
+	CSRFToken string            `json:"csrf_token"`
+	Cookies   map[string]string `json:"cookies"`
+	Options   *cui.Options      `json:"options"`
+}
+
+type Client struct {
+	State
+	http *http.Client
+}
+
+func New(server string) (*Client, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%q is not a server URL", server)
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		State: State{Server: strings.TrimRight(server, "/")},
+		http:  &http.Client{Jar: jar},
+	}, nil
+}
+
+// Resume returns a client for the ticket of state.
+func Resume(state *State) (*Client, error) {
+	c, err := New(state.Server)
+	if err != nil {
+		return nil, err
+	}
+	c.State = *state
+	u, _ := url.Parse(c.Server)
+	cookies := []*http.Cookie{}
+	for name, value := range state.Cookies {
+		cookies = append(cookies, &http.Cookie{Name: name, Value: value})
+	}
+	c.http.Jar.SetCookies(u, cookies)
+	return c, nil
+}
+
+// Save returns the state to Resume from.
+func (c *Client) Save() *State {
+	state := c.State
+	state.Cookies = map[string]string{}
+	u, _ := url.Parse(c.Server)
+	for _, cookie := range c.http.Jar.Cookies(u) {
+		state.Cookies[cookie.Name] = cookie.Value
+	}
+	return &state
+}
+
+func (c *Client) do(req *http.Request, v interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", USER_AGENT)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return ErrStatus{req.Method, req.URL.Path, resp.StatusCode, string(b)}
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(b, v)
+}
+
+func (c *Client) get(path string, v interface{}) error {
+	req, err := http.NewRequest("GET", c.Server+path, nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, v)
+}
+
+// post sends a form about the ticket along with its CSRF token.
+func (c *Client) post(path string, form url.Values, v interface{}) error {
+	form.Set("ticket", c.Ticket)
+	req, err := http.NewRequest("POST", c.Server+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-CSRF-Token", c.CSRFToken)
+	return c.do(req, v)
+}
+
+// NewTicket creates a practice ticket for problem.
+func (c *Client) NewTicket(problem string) (string, error) {
+	reply := map[string]string{}
+	if err := c.get("/cui/new?"+url.Values{"problem_id": {problem}}.Encode(), &reply); err != nil {
+		return "", err
+	}
+	return reply["ticket_id"], nil
+}
+
+// Open binds the ticket to this client, as opening its page binds it to a
+// browser, and starts the clock.
+func (c *Client) Open(ticket string) error {
+	t := &cui.TicketJSON{}
+	if err := c.get("/cui/"+url.QueryEscape(ticket), t); err != nil {
+		return err
+	}
+	c.Ticket, c.CSRFToken, c.Options = t.TicketId, t.CSRFToken, t.Options
+	return c.post("/c/_start", url.Values{}, nil)
+}
+
+// Task fetches a task, switching it to progLang and humanLang unless they
+// are empty.
+func (c *Client) Task(task, progLang, humanLang string) (*cui.TaskJSON, error) {
+	form := url.Values{"task": {task}, "prg_lang": {progLang}, "human_lang": {humanLang}}
+	if progLang != "" {
+		// The server takes the language of the request when this is "false".
+		form.Set("prefer_server_prg_lang", "false")
+	}
+	t := &cui.TaskJSON{}
+	return t, c.post("/c/_get_task", form, t)
+}
+
+// SaveSolution stores the solution without judging it.
+func (c *Client) SaveSolution(task, progLang, solution string) error {
+	return c.post("/chk/save", url.Values{"task": {task}, "prg_lang": {progLang}, "solution": {solution}}, nil)
+}
+
+// Status asks for the verdict of a solution.
+func (c *Client) Status(id string) (*cui.VerifyStatusJSON, error) {
+	v := &cui.VerifyStatusJSON{}
+	return v, c.post("/chk/status", url.Values{"id": {id}}, v)
+}
+
+// Run sends the solution to action ("verify", "judge" or "final") and waits
+// for its verdict. input is the test data of a verify.
+func (c *Client) Run(action, task, progLang, solution, input string) (*cui.VerifyStatusJSON, error) {
+	form := url.Values{"task": {task}, "prg_lang": {progLang}, "solution": {solution}, "test_data0": {input}}
+	v := &cui.VerifyStatusJSON{}
+	if err := c.post("/chk/"+action, form, v); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(Timeout)
+	for v.Result == "LATER" {
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no verdict for %s after %s", v.Id, Timeout)
+		}
+		time.Sleep(Poll)
+		var err error
+		if v, err = c.Status(v.Id); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,99 @@
+package candidate
+
+import (
+	"encoding/json"
+	"github.com/maddyonline/g2/cui"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarkdown(t *testing.T) {
+	desc := cui.RenderDescription("# Sum\n\nPrint *a* + `b` & more.\n\n#### Input\n\n\t1 2\n\t<3>\n\n- one\n- two\n")
+	want := "# Sum\n\nPrint *a* + `b` & more.\n\n#### Input\n\n```\n1 2\n<3>\n```\n\n- one\n- two\n"
+	if got := Markdown(desc); got != want {
+		t.Errorf("Markdown(%q)\n = %q\nwant %q", desc, got, want)
+	}
+}
+
+// fakeServer speaks just enough of the protocol: it checks the cookie and
+// the CSRF token, and makes every solution wait for one status poll.
+func fakeServer(t *testing.T) *httptest.Server {
+	reply := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cui/new", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, map[string]string{"ticket_id": "T1", "problem_id": r.FormValue("problem_id")})
+	})
+	mux.HandleFunc("/cui/T1", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "g2_session", Value: "signed-T1", Path: "/"})
+		opts := cui.DefaultOptions()
+		opts.TaskNames = []string{"sum"}
+		reply(w, &cui.TicketJSON{"T1", "token", opts})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("g2_session")
+		if err != nil || cookie.Value != "signed-T1" || r.Header.Get("X-CSRF-Token") != "token" || r.FormValue("ticket") != "T1" {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/c/_start", "/chk/save":
+		case "/c/_get_task":
+			reply(w, &cui.TaskJSON{Id: r.FormValue("task"), ProgLang: r.FormValue("prg_lang"), Description: "<p>Add.</p>"})
+		case "/chk/verify":
+			reply(w, &cui.VerifyStatusJSON{Result: "LATER", Id: "k1"})
+		case "/chk/status":
+			v := &cui.VerifyStatusJSON{Result: "OK", Id: r.FormValue("id")}
+			v.Extra.Example = cui.StatusJSON{true, "3", nil}
+			reply(w, v)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestSession(t *testing.T) {
+	srv := fakeServer(t)
+	defer srv.Close()
+	Poll = time.Millisecond
+
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket, err := c.NewTicket("sum")
+	if err != nil || ticket != "T1" {
+		t.Fatalf("NewTicket: %q, %v", ticket, err)
+	}
+	if err := c.Open(ticket); err != nil {
+		t.Fatal(err)
+	}
+
+	// A later command picks up where this one left.
+	c, err = Resume(c.Save())
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, err := c.Task(c.Options.TaskNames[0], "py3", "")
+	if err != nil || task.Id != "sum" || task.ProgLang != "py3" {
+		t.Fatalf("Task: %+v, %v", task, err)
+	}
+	if err := c.SaveSolution("sum", "py3", "print(3)"); err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.Run("verify", "sum", "py3", "print(3)", "1 2")
+	if err != nil || v.Result != "OK" || v.Id != "k1" || !v.Extra.Example.OK {
+		t.Fatalf("Run: %+v, %v", v, err)
+	}
+
+	c.CSRFToken = "forged"
+	if _, err := c.Run("verify", "sum", "py3", "", ""); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package candidate
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	preBlock   = regexp.MustCompile(`(?s)<pre><code[^>]*>(.*?)</code></pre>`)
+	heading    = regexp.MustCompile(`<h([1-6])[^>]*>`)
+	link       = regexp.MustCompile(`(?s)<a [^>]*href="([^"]*)"[^>]*>(.*?)</a>`)
+	anyTag     = regexp.MustCompile(`<[^>]+>`)
+	blankLines = regexp.MustCompile(`\n{3,}`)
+	// placeholder stands for the code blocks while the rest is converted.
+	placeholder = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+var inline = strings.NewReplacer(
+	"<p>", "", "</p>", "\n\n",
+	"<br>", "\n", "<br/>", "\n", "<br />", "\n",
+	"<em>", "*", "</em>", "*",
+	"<strong>", "**", "</strong>", "**",
+	"<code>", "`", "</code>", "`",
+	"<li>", "- ", "</li>", "",
+	"<hr>", "\n---\n", "<hr/>", "\n---\n", "<hr />", "\n---\n",
+)
+
+// Markdown turns a task description, which the server renders to HTML,
+// back into markdown for the terminal.
+func Markdown(s string) string {
+	blocks := []string{}
+	s = preBlock.ReplaceAllStringFunc(s, func(m string) string {
+		code := html.UnescapeString(preBlock.FindStringSubmatch(m)[1])
+		if !strings.HasSuffix(code, "\n") {
+			code += "\n"
+		}
+		blocks = append(blocks, "```\n"+code+"```")
+		return fmt.Sprintf("\x00%d\x00", len(blocks)-1)
+	})
+	s = heading.ReplaceAllStringFunc(s, func(m string) string {
+		n := int(heading.FindStringSubmatch(m)[1][0] - '0')
+		return "\n" + strings.Repeat("#", n) + " "
+	})
+	s = link.ReplaceAllString(s, "[$2]($1)")
+	s = inline.Replace(s)
+	for i := 1; i <= 6; i++ {
+		s = strings.Replace(s, fmt.Sprintf("</h%d>", i), "\n\n", -1)
+	}
+	s = html.UnescapeString(anyTag.ReplaceAllString(s, ""))
+	s = blankLines.ReplaceAllString(s, "\n\n")
+	s = placeholder.ReplaceAllStringFunc(s, func(m string) string {
+		var i int
+		fmt.Sscanf(strings.Trim(m, "\x00"), "%d", &i)
+		return blocks[i]
+	})
+	return strings.TrimSpace(s) + "\n"
+}
@@ -0,0 +1,343 @@
+// Package catalog is the searchable list of problems shown on the index
+// page.
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/maddyonline/problems"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Difficulty string
+
+const (
+	EASY   Difficulty = "easy"
+	MEDIUM Difficulty = "medium"
+	HARD   Difficulty = "hard"
+)
+
+var Difficulties = []Difficulty{EASY, MEDIUM, HARD}
+
+const MetaFile = "meta.json"
+
+// Meta is what problems/<name>/meta.json says about a problem. ProgLangs
+// defaults to the languages the problem has templates for.
+type Meta struct {
+	Tags       []string   `json:"tags"`
+	Difficulty Difficulty `json:"difficulty"`
+	ProgLangs  []string   `json:"prg_langs"`
+}
+
+// LoadMeta reads the metadata of a problem; problems without a meta.json
+// have none.
+func LoadMeta(problemsDir, name string) (*Meta, error) {
+	meta := &Meta{}
+	b, err := ioutil.ReadFile(filepath.Join(problemsDir, name, MetaFile))
+	if os.IsNotExist(err) {
+		return meta, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, meta); err != nil {
+		return nil, fmt.Errorf("%s/%s: %v", name, MetaFile, err)
+	}
+	switch meta.Difficulty {
+	case "", EASY, MEDIUM, HARD:
+	default:
+		return nil, fmt.Errorf("%s/%s: unknown difficulty %q", name, MetaFile, meta.Difficulty)
+	}
+	for i, tag := range meta.Tags {
+		meta.Tags[i] = strings.ToLower(strings.TrimSpace(tag))
+	}
+	return meta, nil
+}
+
+type Entry struct {
+	Name       string
+	Title      string
+	ShortDesc  string
+	Tags       []string
+	Difficulty Difficulty
+	ProgLangs  []string
+}
+
+func (e *Entry) Supports(progLang string) bool {
+	for _, l := range e.ProgLangs {
+		if l == progLang {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Entry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+var wordRe = regexp.MustCompile(`[\pL\pN]+`)
+
+func words(s string) []string {
+	return wordRe.FindAllString(strings.ToLower(s), -1)
+}
+
+// Index is an in-memory inverted index over titles, descriptions and tags.
+// It is never modified once built, so it is safe to share.
+type Index struct {
+	entries []*Entry
+	// postings maps a word to the entries containing it and how relevant
+	// the word is there.
+	postings map[string]map[int]int
+	vocab    []string
+	tags     []string
+	langs    []string
+}
+
+// Relevance of a word depending on where it appears.
+const (
+	inTitle = 5
+	inTags  = 3
+	inDesc  = 1
+)
+
+// Build indexes probs. progLangs maps the language names used by tickets
+// to the template names used by problems.
+func Build(probs map[string]*problems.Problem, meta map[string]*Meta, progLangs map[string]string) *Index {
+	ix := &Index{postings: map[string]map[int]int{}}
+	names := []string{}
+	for name := range probs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tags, langs := map[string]bool{}, map[string]bool{}
+	for i, name := range names {
+		p := probs[name]
+		m, ok := meta[name]
+		if !ok {
+			m = &Meta{}
+		}
+		e := &Entry{Name: name, Title: p.Title, ShortDesc: p.ShortDesc, Tags: m.Tags, Difficulty: m.Difficulty, ProgLangs: m.ProgLangs}
+		if len(e.ProgLangs) == 0 {
+			for lang, tmpl := range progLangs {
+				if _, ok := p.Templates[tmpl]; ok {
+					e.ProgLangs = append(e.ProgLangs, lang)
+				}
+			}
+			sort.Strings(e.ProgLangs)
+		}
+		ix.entries = append(ix.entries, e)
+		ix.add(i, inTitle, p.Title, name)
+		ix.add(i, inTags, strings.Join(e.Tags, " "))
+		ix.add(i, inDesc, p.ShortDesc, p.FullDesc)
+		for _, tag := range e.Tags {
+			tags[tag] = true
+		}
+		for _, lang := range e.ProgLangs {
+			langs[lang] = true
+		}
+	}
+	for w := range ix.postings {
+		ix.vocab = append(ix.vocab, w)
+	}
+	sort.Strings(ix.vocab)
+	ix.tags = sortedSet(tags)
+	ix.langs = sortedSet(langs)
+	return ix
+}
+
+// Load builds the index of probs, reading their meta.json from problemsDir.
+// Problems whose metadata cannot be read are still indexed, without it;
+// the errors are returned by problem name.
+func Load(problemsDir string, probs map[string]*problems.Problem, progLangs map[string]string) (*Index, map[string]error) {
+	meta, errs := map[string]*Meta{}, map[string]error{}
+	for name := range probs {
+		m, err := LoadMeta(problemsDir, name)
+		if err != nil {
+			errs[name] = err
+			continue
+		}
+		meta[name] = m
+	}
+	return Build(probs, meta, progLangs), errs
+}
+
+func (ix *Index) add(i, weight int, texts ...string) {
+	for _, text := range texts {
+		for _, w := range words(text) {
+			if ix.postings[w] == nil {
+				ix.postings[w] = map[int]int{}
+			}
+			if weight > ix.postings[w][i] {
+				ix.postings[w][i] = weight
+			}
+		}
+	}
+}
+
+func sortedSet(set map[string]bool) []string {
+	list := []string{}
+	for k := range set {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (ix *Index) Len() int { return len(ix.entries) }
+
+// match scores every entry containing a word starting with prefix.
+func (ix *Index) match(prefix string) map[int]int {
+	scores := map[int]int{}
+	for i := sort.SearchStrings(ix.vocab, prefix); i < len(ix.vocab) && strings.HasPrefix(ix.vocab[i], prefix); i++ {
+		for e, weight := range ix.postings[ix.vocab[i]] {
+			if weight > scores[e] {
+				scores[e] = weight
+			}
+		}
+	}
+	return scores
+}
+
+const PerPage = 20
+
+type Query struct {
+	Text       string
+	Tag        string
+	Difficulty Difficulty
+	ProgLang   string
+	Page       int
+}
+
+// ParseQuery reads a query from the index page URL (q, tag, difficulty,
+// lang and page).
+func ParseQuery(v url.Values) Query {
+	page, _ := strconv.Atoi(v.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	return Query{
+		Text:       strings.TrimSpace(v.Get("q")),
+		Tag:        strings.ToLower(v.Get("tag")),
+		Difficulty: Difficulty(v.Get("difficulty")),
+		ProgLang:   v.Get("lang"),
+		Page:       page,
+	}
+}
+
+func (q Query) values(page int) url.Values {
+	v := url.Values{}
+	for k, s := range map[string]string{"q": q.Text, "tag": q.Tag, "difficulty": string(q.Difficulty), "lang": q.ProgLang} {
+		if s != "" {
+			v.Set(k, s)
+		}
+	}
+	if page > 1 {
+		v.Set("page", strconv.Itoa(page))
+	}
+	return v
+}
+
+// Page is one page of search results along with what the filters can be
+// set to.
+type Page struct {
+	Query
+	Entries      []*Entry
+	Total        int
+	Pages        int
+	Tags         []string
+	Difficulties []Difficulty
+	ProgLangs    []string
+}
+
+// URL links to page n of the same search.
+func (p *Page) URL(n int) string {
+	if v := p.Query.values(n).Encode(); v != "" {
+		return "/?" + v
+	}
+	return "/"
+}
+
+func (p *Page) PageNumbers() []int {
+	list := []int{}
+	for i := 1; i <= p.Pages; i++ {
+		list = append(list, i)
+	}
+	return list
+}
+
+type hit struct {
+	entry *Entry
+	score int
+}
+
+type byScore []hit
+
+func (a byScore) Len() int      { return len(a) }
+func (a byScore) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byScore) Less(i, j int) bool {
+	if a[i].score != a[j].score {
+		return a[i].score > a[j].score
+	}
+	return a[i].entry.Name < a[j].entry.Name
+}
+
+// Search returns the entries matching every word of the query text (as a
+// prefix) and every filter, best matches first.
+func (ix *Index) Search(q Query) *Page {
+	scores := map[int]int{}
+	for i := range ix.entries {
+		scores[i] = 0
+	}
+	for _, w := range words(q.Text) {
+		matched := ix.match(w)
+		for i := range scores {
+			if weight, ok := matched[i]; ok {
+				scores[i] += weight
+			} else {
+				delete(scores, i)
+			}
+		}
+	}
+	hits := []hit{}
+	for i, score := range scores {
+		e := ix.entries[i]
+		if (q.Tag != "" && !e.HasTag(q.Tag)) ||
+			(q.Difficulty != "" && e.Difficulty != q.Difficulty) ||
+			(q.ProgLang != "" && !e.Supports(q.ProgLang)) {
+			continue
+		}
+		hits = append(hits, hit{e, score})
+	}
+	sort.Sort(byScore(hits))
+
+	p := &Page{
+		Query:        q,
+		Total:        len(hits),
+		Pages:        int(math.Ceil(float64(len(hits)) / PerPage)),
+		Tags:         ix.tags,
+		Difficulties: Difficulties,
+		ProgLangs:    ix.langs,
+		Entries:      []*Entry{},
+	}
+	if p.Page < 1 {
+		p.Page = 1
+	}
+	for i := (p.Page - 1) * PerPage; i < len(hits) && i < p.Page*PerPage; i++ {
+		p.Entries = append(p.Entries, hits[i].entry)
+	}
+	return p
+}
@@ -0,0 +1,103 @@
+package catalog
+
+import (
+	"fmt"
+	"github.com/maddyonline/problems"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var progLangs = map[string]string{"cpp": "cpp", "py3": "python"}
+
+func sample() *Index {
+	probs := map[string]*problems.Problem{
+		"add":     {Name: "add", Title: "Add two numbers", ShortDesc: "Sum of a and b", Templates: map[string]string{"cpp": "", "python": ""}},
+		"sort":    {Name: "sort", Title: "Sort an array", ShortDesc: "Sorting", FullDesc: "Return the numbers in increasing order.", Templates: map[string]string{"python": ""}},
+		"islands": {Name: "islands", Title: "Count islands", ShortDesc: "Graph search on a grid", Templates: map[string]string{"cpp": ""}},
+	}
+	meta := map[string]*Meta{
+		"add":     {Difficulty: EASY, Tags: []string{"math"}},
+		"sort":    {Difficulty: MEDIUM, Tags: []string{"arrays", "sorting"}},
+		"islands": {Difficulty: HARD, Tags: []string{"graphs"}},
+	}
+	return Build(probs, meta, progLangs)
+}
+
+func names(p *Page) string {
+	s := ""
+	for _, e := range p.Entries {
+		s += e.Name + " "
+	}
+	return s
+}
+
+func TestSearch(t *testing.T) {
+	ix := sample()
+	for _, c := range []struct {
+		q    Query
+		want string
+	}{
+		{Query{}, "add islands sort "},
+		{Query{Text: "numbers"}, "add sort "}, // title before description
+		{Query{Text: "NUM incr"}, "sort "},
+		{Query{Text: "grap"}, "islands "},
+		{Query{Text: "nothing"}, ""},
+		{Query{Tag: "math"}, "add "},
+		{Query{Difficulty: MEDIUM}, "sort "},
+		{Query{ProgLang: "cpp"}, "add islands "},
+		{Query{Text: "numbers", ProgLang: "cpp"}, "add "},
+	} {
+		if got := names(ix.Search(c.q)); got != c.want {
+			t.Errorf("Search(%+v) = %q, want %q", c.q, got, c.want)
+		}
+	}
+	p := ix.Search(Query{})
+	if fmt.Sprint(p.Tags) != "[arrays graphs math sorting]" || fmt.Sprint(p.ProgLangs) != "[cpp py3]" {
+		t.Errorf("facets: %v %v", p.Tags, p.ProgLangs)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	probs := map[string]*problems.Problem{}
+	for i := 0; i < 45; i++ {
+		name := fmt.Sprintf("p%02d", i)
+		probs[name] = &problems.Problem{Name: name, Title: "Problem " + name}
+	}
+	ix := Build(probs, nil, progLangs)
+	p := ix.Search(ParseQuery(url.Values{"q": {"problem"}, "page": {"3"}}))
+	if p.Total != 45 || p.Pages != 3 || len(p.Entries) != 5 || p.Entries[0].Name != "p40" {
+		t.Errorf("got total %d, pages %d, %q", p.Total, p.Pages, names(p))
+	}
+	if p.URL(2) != "/?page=2&q=problem" || p.URL(1) != "/?q=problem" {
+		t.Errorf("URL(2) = %q, URL(1) = %q", p.URL(2), p.URL(1))
+	}
+	if p := ix.Search(Query{Page: 9}); len(p.Entries) != 0 {
+		t.Errorf("past the last page: %q", names(p))
+	}
+}
+
+func TestLoadMeta(t *testing.T) {
+	dir, err := ioutil.TempDir("", "g2-catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"good": `{"tags": ["Math "], "difficulty": "easy"}`,
+		"bad":  `{"difficulty": "impossible"}`,
+	} {
+		os.MkdirAll(filepath.Join(dir, name), 0755)
+		ioutil.WriteFile(filepath.Join(dir, name, MetaFile), []byte(content), 0644)
+	}
+	probs := map[string]*problems.Problem{"good": {}, "bad": {}, "none": {}}
+	ix, errs := Load(dir, probs, progLangs)
+	if ix.Len() != 3 || len(errs) != 1 || errs["bad"] == nil {
+		t.Fatalf("indexed %d, errors %v", ix.Len(), errs)
+	}
+	if got := names(ix.Search(Query{Tag: "math", Difficulty: EASY})); got != "good " {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/maddyonline/g2/candidate"
+	"github.com/maddyonline/g2/cui"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+const cliUsage = `usage: g2 cli start [-server url] (-problem name | -ticket id)
+       g2 cli task [-task name] [-lang prg_lang] [-human lang] [-o file]
+       g2 cli watch [-task name] [-lang prg_lang] [-every duration] file
+       g2 cli verify [-task name] [-lang prg_lang] file [test.in ...]
+       g2 cli submit [-task name] [-lang prg_lang] file
+Every command takes -state, the file start keeps the ticket in.`
+
+func loadCliState(path string) (*candidate.Client, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s not found; run g2 cli start first", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := &candidate.State{}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return candidate.Resume(state)
+}
+
+func saveCliState(path string, c *candidate.Client) error {
+	b, err := json.MarshalIndent(c.Save(), "", "  ")
+	if err != nil {
+		return err
+	}
+	// Holds the cookie the ticket is bound to.
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// printVerdict reports whether the solution compiled and passed.
+func printVerdict(name string, v *cui.VerifyStatusJSON) bool {
+	ok := v.Extra.Compile.OK && v.Extra.Example.OK
+	if ok {
+		fmt.Printf("ok   %s\n", name)
+	} else {
+		fmt.Printf("FAIL %s\n", name)
+	}
+	for _, s := range []cui.StatusJSON{v.Extra.Compile, v.Extra.Example} {
+		if strings.TrimSpace(s.Message) != "" {
+			fmt.Println(indent(s.Message, "     "))
+		}
+	}
+	return ok
+}
+
+// cliCommand is "g2 cli": it takes an assessment from the terminal through
+// the same protocol as the browser.
+func cliCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(cliUsage)
+	}
+	fs := flag.NewFlagSet("cli "+args[0], flag.ExitOnError)
+	statePath := fs.String("state", ".g2-cli.json", "file keeping the ticket between commands")
+
+	if args[0] == "start" {
+		server := fs.String("server", "http://localhost:"+PORT, "g2 server")
+		problem := fs.String("problem", "", "problem to practice on, in a new ticket")
+		ticket := fs.String("ticket", "", "ticket to take")
+		fs.Parse(args[1:])
+		if (*problem == "") == (*ticket == "") {
+			return errors.New(cliUsage)
+		}
+		c, err := candidate.New(*server)
+		if err != nil {
+			return err
+		}
+		if *problem != "" {
+			if *ticket, err = c.NewTicket(*problem); err != nil {
+				return err
+			}
+		}
+		if err := c.Open(*ticket); err != nil {
+			return err
+		}
+		fmt.Printf("Ticket %s: %s\n", c.Ticket, strings.Join(c.Options.TaskNames, ", "))
+		return saveCliState(*statePath, c)
+	}
+
+	taskName := fs.String("task", "", "task (default the current one)")
+	progLang := fs.String("lang", "", "programming language (default the current one)")
+	humanLang := fs.String("human", "", "human language of the description")
+	out := fs.String("o", "", "file to write the solution template to, unless it exists")
+	every := fs.Duration("every", time.Second, "how often to check the file for changes")
+	fs.Parse(args[1:])
+	c, err := loadCliState(*statePath)
+	if err != nil {
+		return err
+	}
+	if *taskName == "" {
+		*taskName = c.Options.CurrentTaskName
+	}
+	if *progLang == "" {
+		*progLang = c.Options.CurrentProgLang
+	}
+	c.Options.CurrentTaskName, c.Options.CurrentProgLang = *taskName, *progLang
+	defer saveCliState(*statePath, c)
+
+	switch args[0] {
+	case "task":
+		task, err := c.Task(*taskName, *progLang, *humanLang)
+		if err != nil {
+			return err
+		}
+		fmt.Print(candidate.Markdown(task.Description))
+		if *out == "" {
+			return nil
+		}
+		if _, err := os.Stat(*out); err == nil {
+			return nil
+		}
+		return ioutil.WriteFile(*out, []byte(task.CurrentSolution), 0644)
+	case "watch":
+		if fs.NArg() != 1 {
+			return errors.New(cliUsage)
+		}
+		last := ""
+		for {
+			b, err := ioutil.ReadFile(fs.Arg(0))
+			if err == nil && string(b) != last {
+				if err := c.SaveSolution(*taskName, *progLang, string(b)); err != nil {
+					return err
+				}
+				last = string(b)
+				fmt.Printf("%s saved %s\n", time.Now().Format("15:04:05"), fs.Arg(0))
+			}
+			time.Sleep(*every)
+		}
+	case "verify", "submit":
+		if fs.NArg() == 0 || (args[0] == "submit" && fs.NArg() != 1) {
+			return errors.New(cliUsage)
+		}
+		solution, err := ioutil.ReadFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		if args[0] == "submit" {
+			v, err := c.Run("final", *taskName, *progLang, string(solution), "")
+			if err != nil {
+				return err
+			}
+			if !printVerdict(*taskName, v) {
+				return errors.New("the solution failed")
+			}
+			return nil
+		}
+		tests := fs.Args()[1:]
+		if len(tests) == 0 {
+			// The example of the task.
+			tests = []string{""}
+		}
+		failed := 0
+		for _, path := range tests {
+			input, name := []byte{}, "example"
+			if path != "" {
+				if input, err = ioutil.ReadFile(path); err != nil {
+					return err
+				}
+				name = path
+			}
+			v, err := c.Run("verify", *taskName, *progLang, string(solution), string(input))
+			if err != nil {
+				return err
+			}
+			if !printVerdict(name, v) {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d runs failed", failed, len(tests))
+		}
+		return nil
+	}
+	return errors.New(cliUsage)
+}
@@ -0,0 +1,224 @@
+// Package compile checks that solutions compile before they are run, and
+// turns compiler output into diagnostics the editor can show on the lines
+// they are about.
+package compile
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ERROR   = "error"
+	WARNING = "warning"
+	INFO    = "info"
+)
+
+// Timeout bounds a single compilation.
+var Timeout = 30 * time.Second
+
+type ErrNoCompiler struct {
+	ProgLang string
+}
+
+func (e ErrNoCompiler) Error() string {
+	return fmt.Sprintf("No compiler for %s", e.ProgLang)
+}
+
+// Diagnostic is a message of the compiler about a place in the solution.
+// Lines and columns count from 1; a column of 0 means the whole line.
+type Diagnostic struct {
+	Line     int    `xml:"line" json:"line"`
+	Column   int    `xml:"column" json:"column"`
+	Severity string `xml:"severity" json:"severity"`
+	Message  string `xml:"message" json:"message"`
+}
+
+type Result struct {
+	OK          bool
+	Output      string
+	Diagnostics []Diagnostic
+}
+
+// Compilers maps CUI programming languages to a command that reads a
+// solution on stdin and fails when it does not compile.
+type Compilers map[string][]string
+
+func sandboxed(image, script string) []string {
+	return []string{
+		"docker", "run", "--rm", "-i", "--network=none",
+		"--memory=512m", "--cpus=1", "--pids-limit=128", "--read-only",
+		"--tmpfs=/tmp", "--tmpfs=/workspace", "-e", "HOME=/tmp", "-w", "/workspace",
+		image, "sh", "-c", script,
+	}
+}
+
+func DefaultCompilers() Compilers {
+	return Compilers{
+		"c":   sandboxed("gcc", "cat > main.c && gcc -fsyntax-only -Wall main.c"),
+		"cpp": sandboxed("gcc", "cat > main.cpp && g++ -std=c++14 -fsyntax-only -Wall main.cpp"),
+		"go":  sandboxed("golang", "cat > main.go && go build -o /dev/null main.go"),
+		"py2": sandboxed("python:2", "cat > main.py && python -m py_compile main.py"),
+		"py3": sandboxed("python:3", "cat > main.py && python -m py_compile main.py"),
+	}
+}
+
+// LoadCompilers reads compilers from a JSON file of the form
+// {"cpp": ["sh", "-c", "cat > main.cpp && g++ -fsyntax-only main.cpp"], ...}.
+func LoadCompilers(path string) (Compilers, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	compilers := Compilers{}
+	if err := json.Unmarshal(b, &compilers); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for lang, command := range compilers {
+		if len(command) == 0 {
+			return nil, fmt.Errorf("%s: no command for %s", path, lang)
+		}
+	}
+	return compilers, nil
+}
+
+// named names the container of a "docker run" command, so that it can be
+// removed: killing the docker client leaves its container running. Other
+// commands are returned as they are, with no name.
+func named(command []string) ([]string, string) {
+	if len(command) < 2 || filepath.Base(command[0]) != "docker" || command[1] != "run" {
+		return command, ""
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	name := "g2-compile-" + hex.EncodeToString(b)
+	return append([]string{command[0], "run", "--name", name}, command[2:]...), name
+}
+
+// Check compiles source. A solution that does not compile is not an error;
+// the result says so.
+func (cs Compilers) Check(progLang, source string) (*Result, error) {
+	command, ok := cs[progLang]
+	if !ok {
+		return nil, ErrNoCompiler{progLang}
+	}
+	command, container := named(command)
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = strings.NewReader(source)
+	out := &bytes.Buffer{}
+	cmd.Stdout, cmd.Stderr = out, out
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	timer := time.AfterFunc(Timeout, func() { cmd.Process.Kill() })
+	err := cmd.Wait()
+	if !timer.Stop() {
+		if container != "" {
+			exec.Command(cmd.Path, "rm", "-f", container).Run()
+		}
+		return nil, fmt.Errorf("compiling took over %s", Timeout)
+	}
+	if _, failed := err.(*exec.ExitError); err != nil && !failed {
+		return nil, err
+	}
+	output := out.String()
+	return &Result{err == nil, output, Parse(progLang, source, output)}, nil
+}
+
+var (
+	// main.cpp:3:5: error: 'x' was not declared in this scope
+	gccLine = regexp.MustCompile(`^(\S+?):(\d+):(?:(\d+):)? (fatal error|error|warning|note): (.*)$`)
+	// ./main.go:3:5: undefined: x
+	goLine = regexp.MustCompile(`^(\S+?\.go):(\d+)(?::(\d+))?: (.*)$`)
+	// File "main.py", line 3
+	pyFile  = regexp.MustCompile(`^\s*File "([^"]+)", line (\d+)`)
+	pyError = regexp.MustCompile(`^(\w*(?:Error|Warning)): (.*)$`)
+)
+
+// ofSolution tells messages about the solution from those about headers
+// and the like.
+func ofSolution(file string) bool {
+	return strings.HasPrefix(path.Base(file), "main.")
+}
+
+// Parse extracts diagnostics from the output of the compiler of progLang.
+func Parse(progLang, source, output string) []Diagnostic {
+	lines := strings.Split(strings.Replace(output, "\r\n", "\n", -1), "\n")
+	switch progLang {
+	case "py2", "py3":
+		return parsePython(strings.Split(source, "\n"), lines)
+	case "go":
+		return parseLines(lines, goLine, func(m []string) Diagnostic {
+			return Diagnostic{atoi(m[2]), atoi(m[3]), ERROR, m[4]}
+		})
+	default:
+		return parseLines(lines, gccLine, func(m []string) Diagnostic {
+			severity := map[string]string{"fatal error": ERROR, "error": ERROR, "warning": WARNING, "note": INFO}[m[4]]
+			return Diagnostic{atoi(m[2]), atoi(m[3]), severity, m[5]}
+		})
+	}
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func parseLines(lines []string, re *regexp.Regexp, diagnostic func(m []string) Diagnostic) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, line := range lines {
+		if m := re.FindStringSubmatch(line); m != nil && ofSolution(m[1]) {
+			diagnostics = append(diagnostics, diagnostic(m))
+		}
+	}
+	return diagnostics
+}
+
+// parsePython reads a syntax error: the last frame in the solution, the
+// offending line, a caret under the error and the error itself.
+func parsePython(source, lines []string) []Diagnostic {
+	d := Diagnostic{Severity: ERROR}
+	for i, line := range lines {
+		if m := pyFile.FindStringSubmatch(line); m != nil {
+			if ofSolution(m[1]) {
+				d.Line, d.Column = atoi(m[2]), 0
+				if i+2 < len(lines) && strings.Trim(lines[i+2], " ^~") == "" && strings.Contains(lines[i+2], "^") {
+					d.Column = caretColumn(source, d.Line, lines[i+1], lines[i+2])
+				}
+			}
+			continue
+		}
+		if m := pyError.FindStringSubmatch(line); m != nil {
+			d.Message = m[1] + ": " + m[2]
+		}
+	}
+	if d.Line == 0 || d.Message == "" {
+		return []Diagnostic{}
+	}
+	return []Diagnostic{d}
+}
+
+// caretColumn maps the caret under the line Python shows, which it strips
+// of its indentation, back to a column of the source.
+func caretColumn(source []string, n int, shown, caret string) int {
+	indent := func(s string) int { return len(s) - len(strings.TrimLeft(s, " \t")) }
+	col := strings.Index(caret, "^") - indent(shown)
+	if n-1 < len(source) {
+		col += indent(source[n-1])
+	}
+	if col < 0 {
+		return 0
+	}
+	return col + 1
+}
@@ -0,0 +1,86 @@
+package compile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		progLang string
+		source   string
+		output   string
+		want     []Diagnostic
+	}{
+		{"cpp", "", `main.cpp: In function 'int main()':
+main.cpp:4:3: error: 'x' was not declared in this scope
+main.cpp:2:7: warning: unused variable 'y' [-Wunused-variable]
+/usr/include/c++/9/bits/stl_vector.h:10:2: error: in a header
+main.cpp:1:1: note: declared here
+`, []Diagnostic{
+			{4, 3, ERROR, "'x' was not declared in this scope"},
+			{2, 7, WARNING, "unused variable 'y' [-Wunused-variable]"},
+			{1, 1, INFO, "declared here"},
+		}},
+		{"c", "", "main.c:5: error: old gcc, no column\n", []Diagnostic{
+			{5, 0, ERROR, "old gcc, no column"},
+		}},
+		{"go", "", `# command-line-arguments
+./main.go:6:2: undefined: x
+./main.go:9: missing return
+`, []Diagnostic{
+			{6, 2, ERROR, "undefined: x"},
+			{9, 0, ERROR, "missing return"},
+		}},
+		{"py3", "def f():\n    print(1 +)\n", `  File "main.py", line 2
+    print(1 +)
+             ^
+SyntaxError: invalid syntax
+`, []Diagnostic{
+			{2, 14, ERROR, "SyntaxError: invalid syntax"},
+		}},
+		{"py3", "def f():\nreturn 1\n", `Traceback (most recent call last):
+  File "/usr/lib/python3/py_compile.py", line 144, in compile
+  File "main.py", line 2
+    return 1
+    ^^^^^^
+IndentationError: expected an indented block
+`, []Diagnostic{
+			{2, 1, ERROR, "IndentationError: expected an indented block"},
+		}},
+		{"py2", "", "", []Diagnostic{}},
+	} {
+		if got := Parse(tc.progLang, tc.source, tc.output); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Parse(%s, %q)\n = %+v\nwant %+v", tc.progLang, tc.output, got, tc.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cs := Compilers{
+		"cpp": {"sh", "-c", "cat > /dev/null"},
+		"go":  {"sh", "-c", "cat > /dev/null; echo './main.go:1:1: expected package'; exit 2"},
+	}
+	res, err := cs.Check("cpp", "int main() {}")
+	if err != nil || !res.OK || len(res.Diagnostics) != 0 {
+		t.Errorf("Check(cpp) = %+v, %v", res, err)
+	}
+	res, err = cs.Check("go", "func main() {}")
+	if err != nil || res.OK || !reflect.DeepEqual(res.Diagnostics, []Diagnostic{{1, 1, ERROR, "expected package"}}) {
+		t.Errorf("Check(go) = %+v, %v", res, err)
+	}
+	if _, err := cs.Check("py3", ""); err == nil {
+		t.Error("Check(py3) succeeded without a compiler")
+	}
+}
+
+func TestNamed(t *testing.T) {
+	command, name := named(DefaultCompilers()["cpp"])
+	if !strings.HasPrefix(name, "g2-compile-") || command[2] != "--name" || command[3] != name {
+		t.Errorf("named: %v, %q", command, name)
+	}
+	if command, name := named([]string{"sh", "-c", "true"}); name != "" || len(command) != 3 {
+		t.Errorf("named a plain command: %v, %q", command, name)
+	}
+}
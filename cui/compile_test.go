@@ -0,0 +1,62 @@
+package cui
+
+import (
+	"github.com/maddyonline/g2/cache"
+	"github.com/maddyonline/g2/compile"
+	"strings"
+	"testing"
+)
+
+func TestCompileFirst(t *testing.T) {
+	client := &Client{Compilers: compile.Compilers{
+		"cpp": {"sh", "-c", "grep -q main || { echo 'main.cpp:1:1: error: no main'; exit 1; }"},
+	}}
+
+	task := &Task{Id: "sum", ProgLang: "cpp", HumanLang: "en", CurrentSolution: "int x;"}
+	resp := defaultVerifyStatus("en")
+	if client.compile(task, resp) {
+		t.Fatal("a solution that does not compile would be run")
+	}
+	if resp.Extra.Compile.OK != 0 || !strings.Contains(resp.Extra.Compile.Message, "no main") ||
+		len(resp.Extra.Compile.Diagnostics) != 1 || resp.Extra.Compile.Diagnostics[0].Line != 1 {
+		t.Errorf("compile status %+v", resp.Extra.Compile)
+	}
+	if resp.Extra.Example.OK != 0 || resp.Extra.Example.Message != T("en", MSG_NOT_RUN) {
+		t.Errorf("example status %+v", resp.Extra.Example)
+	}
+
+	task.CurrentSolution = "int main() {}"
+	resp = defaultVerifyStatus("en")
+	if !client.compile(task, resp) || resp.Extra.Compile.OK != 1 {
+		t.Errorf("compiling solution: %+v", resp.Extra.Compile)
+	}
+
+	// Languages without a compiler go straight to the judge.
+	task.ProgLang = "py3"
+	if !client.compile(task, defaultVerifyStatus("en")) {
+		t.Error("py3 was not left to the judge")
+	}
+}
+
+func TestCompileCached(t *testing.T) {
+	client := &Client{
+		Compilers: compile.Compilers{"cpp": {"sh", "-c", "cat > /dev/null"}},
+		Cache:     cache.New(1 << 20),
+	}
+	task := &Task{Id: "sum", ProgLang: "cpp", HumanLang: "en", CurrentSolution: "int main() {}"}
+	if !client.compile(task, defaultVerifyStatus("en")) {
+		t.Fatal("did not compile")
+	}
+	// Only a compiler that is not asked again keeps giving the same answer.
+	client.Compilers["cpp"] = []string{"false"}
+	if !client.compile(task, defaultVerifyStatus("en")) {
+		t.Error("the same solution was compiled again")
+	}
+	task.CurrentSolution += "\n"
+	if client.compile(task, defaultVerifyStatus("en")) {
+		t.Error("a changed solution got the cached result")
+	}
+	if s := client.Cache.Stats(); s.Hits != 1 || s.Misses != 2 {
+		t.Errorf("Stats() = %+v", s)
+	}
+}
@@ -7,10 +7,15 @@ import (
 	"encoding/xml"
 	"fmt"
 	"github.com/labstack/gommon/log"
+	"github.com/maddyonline/g2/cache"
+	"github.com/maddyonline/g2/catalog"
+	"github.com/maddyonline/g2/compile"
+	"github.com/maddyonline/g2/logging"
 	"github.com/maddyonline/problems"
 	"github.com/maddyonline/umpire"
 	"github.com/microcosm-cc/bluemonday"
 	"github.com/russross/blackfriday"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -30,11 +35,33 @@ func RandId(size int) string {
 }
 
 type Client struct {
-	Agent       *umpire.Agent
-	ProbsList   map[string]*problems.Problem
-	Index       []byte
+	Agent     *umpire.Agent
+	ProbsList map[string]*problems.Problem
+	// Versions holds the current version of every problem in ProbsList.
+	Versions    map[string]string
+	Catalog     *catalog.Index
 	LastUpdated time.Time
+	// OnVerdict, if set, is called once the verdict of a submission is known.
+	OnVerdict func(*Session, *Submission)
+	// AgentFor, if set, returns the agent judging against the problem
+	// version a task was created with. Agent judges everything else.
+	AgentFor func(task *Task) *umpire.Agent
+	// Translations are the translated statements of the problems, by
+	// problem and human language.
+	Translations map[string]map[string]string
+	// Compilers, if set, check solutions before they are run, so ones that
+	// do not compile are answered without starting the judge.
+	Compilers compile.Compilers
+	// Cache, if set, keeps compile and run results so identical requests
+	// are answered without running them again.
+	Cache *cache.Cache
+	// OnExecutorFailure, if set, is called when a solution could not be
+	// compiled or run for reasons of the executor rather than the solution.
+	OnExecutorFailure func(task *Task, err error)
 	*sync.Mutex
+
+	// pending counts the solutions being judged.
+	pending sync.WaitGroup
 }
 
 type HumanLang struct {
@@ -60,6 +87,7 @@ type Options struct {
 	ShowWelcome      bool                 `json:"show_welcome"`
 	Sequential       bool                 `json:"sequential"`
 	SaveOften        bool                 `json:"save_often"`
+	LanguageServer   bool                 `json:"language_server"`
 	Urls             map[string]string    `json:"urls"`
 }
 
@@ -68,14 +96,6 @@ type Ticket struct {
 	Options *Options
 }
 
-type Session struct {
-	Ticket    *Ticket
-	StartTime time.Time
-	Created   time.Time
-	Started   bool
-	TimeLimit int
-}
-
 type TaskKey struct {
 	TicketId string
 	TaskId   string
@@ -105,6 +125,9 @@ type Task struct {
 	Src              string            `xml:"-"`
 	Filename         string            `xml:"-"`
 	Templates        map[string]string `xml:"-"`
+	ProblemVersion   string            `xml:"-"`
+	// Descriptions are the rendered statement by human language.
+	Descriptions map[string]string `xml:"-"`
 }
 
 type ClockRequest struct {
@@ -132,6 +155,8 @@ type SolutionRequest struct {
 type Status struct {
 	OK      int    `xml:"ok"`
 	Message string `xml:"message"`
+	// Diagnostics place the compiler's messages in the solution.
+	Diagnostics []compile.Diagnostic `xml:"diagnostic"`
 }
 type MainStatus struct {
 	Compile   Status `xml:"compile"`
@@ -164,29 +189,107 @@ var CUI_LANG_TO_BACKEND = map[string]string{
 	"py3": "python",
 }
 
-func (client *Client) NewTicket(tasks map[TaskKey]*Task, taskId string) *Ticket {
+type ErrUnknownProblem struct {
+	Id string
+}
+
+func (e ErrUnknownProblem) Error() string {
+	return fmt.Sprintf("Unknown problem: %s", e.Id)
+}
+
+type ErrUnknownProgLang struct {
+	Name string
+}
+
+func (e ErrUnknownProgLang) Error() string {
+	return fmt.Sprintf("Unknown programming language: %s", e.Name)
+}
+
+// NewTicket creates a ticket for the given problems, one task per problem.
+// The candidate may only pick from progLangs; an empty list allows every
+// language in DefaultProgLangList.
+func (client *Client) NewTicket(tasks map[TaskKey]*Task, taskIds []string, progLangs []string) (*Ticket, error) {
+	if len(taskIds) == 0 {
+		return nil, ErrUnknownProblem{}
+	}
+	langList, err := progLangWhitelist(progLangs)
+	if err != nil {
+		return nil, err
+	}
 	ticketId := RandId(32)
-	task := NewTask()
-	task.Id = taskId
-	prob := client.ProbsList[taskId]
-
-	log.Infof("prob: %+v", prob)
-
-	task.Description = string(getDescFromMarkdown([]byte(prob.FullDesc)))
-	task.Templates = prob.Templates
-	task.SolutionTemplate = prob.Templates[CUI_LANG_TO_MD[task.ProgLang]]
-	task.CurrentSolution = prob.Templates[CUI_LANG_TO_MD[task.ProgLang]]
-	tasks[TaskKey{ticketId, taskId}] = task
-	log.Infof("%#v", tasks)
+	client.Lock()
+	probs := []*problems.Problem{}
+	versions := []string{}
+	translations := []map[string]string{}
+	for _, taskId := range taskIds {
+		prob, ok := client.ProbsList[taskId]
+		if !ok {
+			client.Unlock()
+			return nil, ErrUnknownProblem{taskId}
+		}
+		probs = append(probs, prob)
+		versions = append(versions, client.Versions[taskId])
+		translations = append(translations, client.Translations[taskId])
+	}
+	client.Unlock()
+
+	var progLang string
+	for i, prob := range probs {
+		task := NewTask()
+		task.Id = taskIds[i]
+		task.ProblemVersion = versions[i]
+		task.ProgLangList = langListJSON(langList)
+		if _, ok := langList[task.ProgLang]; !ok {
+			task.ProgLang = sortedKeys(langList)[0]
+		}
+		progLang = task.ProgLang
+		task.Descriptions = descriptions(prob, translations[i])
+		task.HumanLangList = humanLangListJSON(task.Descriptions)
+		task.SetHumanLang(task.HumanLang)
+		task.Templates = prob.Templates
+		task.SolutionTemplate = prob.Templates[CUI_LANG_TO_MD[task.ProgLang]]
+		task.CurrentSolution = prob.Templates[CUI_LANG_TO_MD[task.ProgLang]]
+		tasks[TaskKey{ticketId, task.Id}] = task
+	}
 	opts := DefaultOptions()
 	opts.TicketId = ticketId
-	taskIds := []string{task.Id}
 	opts.TaskNames = taskIds
-	opts.CurrentTaskName = task.Id
-	opts.CurrentProgLang = task.ProgLang
+	opts.CurrentTaskName = taskIds[0]
+	opts.CurrentProgLang = progLang
+	opts.ProgLangList = langList
 	opts.Urls["close"] = strings.Replace(opts.Urls["close"], "TICKET_ID", opts.TicketId, -1)
 	opts.Urls["submit_survey"] = strings.Replace(opts.Urls["submit_survey"], "TICKET_ID", opts.TicketId, -1)
-	return &Ticket{Id: ticketId, Options: opts}
+	return &Ticket{Id: ticketId, Options: opts}, nil
+}
+
+func progLangWhitelist(progLangs []string) (map[string]ProgLang, error) {
+	all := DefaultProgLangList()
+	if len(progLangs) == 0 {
+		return all, nil
+	}
+	list := map[string]ProgLang{}
+	for _, name := range progLangs {
+		lang, ok := all[name]
+		if !ok {
+			return nil, ErrUnknownProgLang{name}
+		}
+		list[name] = lang
+	}
+	return list, nil
+}
+
+func sortedKeys(list map[string]ProgLang) []string {
+	keys := []string{}
+	for k := range list {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func langListJSON(list map[string]ProgLang) string {
+	prg_lang_list, _ := json.Marshal(sortedKeys(list))
+	return string(prg_lang_list)
 }
 
 func DefaultHumanLangList() map[string]HumanLang {
@@ -234,6 +337,7 @@ func DefaultOptions() *Options {
 			"timeout_action": "/chk/timeout_action/",
 			"final":          "/chk/final/",
 			"start_ticket":   "/c/_start/",
+			"lsp":            "/lsp/",
 		},
 	}
 	return opts
@@ -260,19 +364,18 @@ func (t Mode) String() string {
 	return val
 }
 
-func errorReply(err error, v *VerifyStatus) *VerifyStatus {
+func errorReply(lang string, err error, v *VerifyStatus) *VerifyStatus {
 	v.Extra.Compile.OK = 0
-	v.Extra.Compile.Message = fmt.Sprintf("Something went wrong: %v", err)
+	v.Extra.Compile.Message = T(lang, MSG_WENT_WRONG_WITH, err)
 	v.Extra.Example.OK = 0
-	v.Extra.Example.Message = "Something went wrong"
+	v.Extra.Example.Message = T(lang, MSG_WENT_WRONG)
 	return v
 }
 
-func LaterReply(key string) *VerifyStatus {
-	log.Info("laterReply")
+func LaterReply(key, lang string) *VerifyStatus {
 	resp := &VerifyStatus{
 		Result:  "LATER",
-		Message: "We are still evaluating the solution",
+		Message: T(lang, MSG_EVALUATING),
 		Id:      key,
 		Delay:   60,
 	}
@@ -307,45 +410,136 @@ func getPayload(task *Task, solnReq *SolutionRequest) *umpire.Payload {
 	}
 }
 
-func defaultVerifyStatus() *VerifyStatus {
+func defaultVerifyStatus(lang string) *VerifyStatus {
+	ok := T(lang, MSG_OK)
 	return &VerifyStatus{
 		Result: "OK",
 		Extra: MainStatus{
-			Compile:   Status{1, "The solution compiled flawlessly."},
-			Example:   Status{1, "OK"},
-			TestData0: Status{1, "OK"},
-			TestData1: Status{1, "OK"},
-			TestData2: Status{1, "OK"},
-			TestData3: Status{1, "OK"},
-			TestData4: Status{1, "OK"},
+			Compile:   Status{1, T(lang, MSG_COMPILED), nil},
+			Example:   Status{1, ok, nil},
+			TestData0: Status{1, ok, nil},
+			TestData1: Status{1, ok, nil},
+			TestData2: Status{1, ok, nil},
+			TestData3: Status{1, ok, nil},
+			TestData4: Status{1, ok, nil},
 		},
 	}
 }
 
-func (client *Client) GetVerifyStatus(task *Task, solnReq *SolutionRequest, mode Mode) *VerifyStatus {
-	log.Infof("In VerifyStatus, mode=%s", mode)
+// compile fills in the compile status of resp and reports whether the
+// solution is worth running. Without a compiler for the language, that is
+// left to the judge.
+func (client *Client) compile(task *Task, resp *VerifyStatus) bool {
+	if _, ok := client.Compilers[task.ProgLang]; !ok {
+		return true
+	}
+	key := cache.Key{Kind: cache.COMPILE, ProgLang: task.ProgLang, Source: cache.Hash(task.CurrentSolution)}
+	var res *compile.Result
+	if v, ok := client.Cache.Get(key); ok {
+		res = v.(*compile.Result)
+	} else {
+		var err error
+		if res, err = client.Compilers.Check(task.ProgLang, task.CurrentSolution); err != nil {
+			log.Warnj(logging.Line("Compiler failed", logging.TASK_ID, task.Id, "prg_lang", task.ProgLang, "error", err.Error()))
+			client.executorFailed(task, err)
+			return true
+		}
+		client.Cache.Put(key, res, int64(len(res.Output)+len(res.Diagnostics)*64))
+	}
+	resp.Extra.Compile.Diagnostics = res.Diagnostics
+	if res.OK {
+		return true
+	}
+	lang := task.HumanLang
+	resp.Extra.Compile = Status{0, T(lang, MSG_NOT_COMPILED, res.Output), res.Diagnostics}
+	notRun := Status{0, T(lang, MSG_NOT_RUN), nil}
+	resp.Extra.Example = notRun
+	resp.Extra.TestData0, resp.Extra.TestData1, resp.Extra.TestData2 = notRun, notRun, notRun
+	resp.Extra.TestData3, resp.Extra.TestData4 = notRun, notRun
+	return false
+}
+
+// run runs or judges the solution, unless the same solution already passed
+// on the same input against the same problem version.
+func (client *Client) run(agent *umpire.Agent, task *Task, payload *umpire.Payload, mode Mode) *umpire.Response {
+	version := task.ProblemVersion
+	if version == "" {
+		client.Lock()
+		version = client.Versions[task.Id]
+		client.Unlock()
+	}
+	key := cache.Key{Kind: cache.RUN, Problem: task.Id, Version: version, ProgLang: task.ProgLang,
+		Source: cache.Hash(task.CurrentSolution), Input: cache.Hash(payload.Stdin)}
+	if mode != VERIFY {
+		// Judging runs the problem's tests, whatever the candidate's input.
+		key.Kind, key.Input = cache.JUDGE, ""
+	}
+	if v, ok := client.Cache.Get(key); ok {
+		return v.(*umpire.Response)
+	}
+	var out *umpire.Response
+	switch mode {
+	case VERIFY:
+		out = umpire.RunDefault(agent, payload)
+	case JUDGE, FINAL:
+		out = umpire.JudgeDefault(agent, payload)
+	}
+	if out.Status != umpire.Pass && out.Status != umpire.Fail {
+		// Not a verdict, so not worth keeping either.
+		client.executorFailed(task, fmt.Errorf("judge status %q: %s", out.Status, out.Details))
+		return out
+	}
+	if out.Status == umpire.Fail {
+		// A failure may be a time limit hit on a busy host, which the same
+		// solution can pass when run again.
+		return out
+	}
+	client.Cache.Put(key, out, int64(len(out.Stdout)+len(out.Stderr)+len(out.Details)))
+	return out
+}
+
+func (client *Client) executorFailed(task *Task, err error) {
+	if client.OnExecutorFailure != nil {
+		client.OnExecutorFailure(task, err)
+	}
+}
+
+func (client *Client) GetVerifyStatus(session *Session, task *Task, solnReq *SolutionRequest, mode Mode) *VerifyStatus {
 	verifyKey := RandId(4)
-	log.Infof("Client: %+v", client)
+	sub := session.AddSubmission(verifyKey, task, mode)
+	log.Debugj(logging.Line("Judging",
+		logging.TICKET_ID, solnReq.Ticket,
+		logging.TASK_ID, task.Id,
+		"submission_id", verifyKey,
+		"mode", mode.String(),
+		"prg_lang", task.ProgLang,
+		"solution", logging.Solution(task.CurrentSolution)))
 	agent := client.Agent
+	if client.AgentFor != nil && task.ProblemVersion != "" {
+		agent = client.AgentFor(task)
+	}
 	payload := getPayload(task, solnReq)
+	lang := task.HumanLang
 	done := make(chan *VerifyStatus)
+	client.pending.Add(1)
 	go func() {
-		var out *umpire.Response
-		switch mode {
-		case VERIFY:
-			out = umpire.RunDefault(agent, payload)
-		case JUDGE, FINAL:
-			out = umpire.JudgeDefault(agent, payload)
-		}
-		resp := defaultVerifyStatus()
-		msg, _ := json.Marshal(out)
-		resp.Extra.Example.Message = out.Stdout + "\n" + out.Stderr + "\n" + out.Details + "\n" + string(msg)
-		if out.Status == umpire.Fail {
-			resp.Extra.Example.OK = 0
+		defer client.pending.Done()
+		resp := defaultVerifyStatus(lang)
+		if compiled := client.compile(task, resp); compiled {
+			out := client.run(agent, task, payload, mode)
+			msg, _ := json.Marshal(out)
+			resp.Extra.Example.Message = out.Stdout + "\n" + out.Stderr + "\n" + out.Details + "\n" + string(msg)
+			if out.Status == umpire.Fail {
+				resp.Extra.Example.OK = 0
+			}
 		}
 		Results.Lock()
 		Results.Store[fmt.Sprintf("%s/%s", solnReq.Ticket, verifyKey)] = resp
 		Results.Unlock()
+		session.SetVerdict(sub, resp)
+		if client.OnVerdict != nil {
+			client.OnVerdict(session, sub)
+		}
 		done <- resp
 	}()
 	for {
@@ -354,23 +548,28 @@ func (client *Client) GetVerifyStatus(task *Task, solnReq *SolutionRequest, mode
 			return resp
 		case <-time.After(1 * time.Second):
 			go func() { <-done }()
-			return LaterReply(verifyKey)
+			return LaterReply(verifyKey, lang)
 		}
 	}
 }
 
+// Wait blocks until every solution being judged has its verdict.
+func (client *Client) Wait() {
+	client.pending.Wait()
+}
+
 func (client *Client) GetClock(sessions map[string]*Session, clkReq *ClockRequest) *ClockResponse {
 	session, ok := sessions[clkReq.TicketId]
 	if !ok {
 		return &ClockResponse{Result: "OK", NewTimeLimit: clkReq.OldTimeLimit}
 	}
+	session.Refresh()
 	elapsed := int(time.Since(session.StartTime) / time.Second)
 	remaining := session.TimeLimit - elapsed
-	log.Info("elapsed: %s, remaining: %s", time.Duration(elapsed)*time.Second, time.Duration(remaining)*time.Second)
 	if remaining < 0 {
 		remaining = 0
 	}
-	log.Info("newTimeLimit: %v, that is, %s", remaining, time.Duration(remaining)*time.Second)
+	log.Debugj(logging.Line("Clock", logging.TICKET_ID, clkReq.TicketId, "elapsed_sec", elapsed, "remaining_sec", remaining))
 	return &ClockResponse{Result: "OK", NewTimeLimit: remaining}
 }
 
@@ -417,6 +616,12 @@ func getDescFromMarkdown(input []byte) []byte {
 	return html
 }
 
+// RenderDescription renders a problem statement the way candidates see it,
+// for previews.
+func RenderDescription(markdown string) string {
+	return string(getDescFromMarkdown([]byte(markdown)))
+}
+
 func NewTask() *Task {
 	task := &Task{
 		Id:               "",
@@ -440,7 +645,6 @@ func ProgrammingLanguageList() string {
 	for k := range list {
 		keys = append(keys, k)
 	}
-	log.Printf("Loading following languages: %v", keys)
 	prg_lang_list, _ := json.Marshal(keys)
 	return string(prg_lang_list)
 }
@@ -453,10 +657,8 @@ func HumanLanguageList() string {
 func (client *Client) GetTask(tasks map[TaskKey]*Task, msg *TaskRequest) *Task {
 	key := TaskKey{msg.Ticket, msg.Task}
 	task, ok := tasks[key]
-	log.Info(fmt.Sprintf("Looking for %s in tasks: %v", key, ok))
-
 	if !ok || task == nil {
-		log.Info("Serving task based on nil request")
+		log.Warnj(logging.Line("Serving a placeholder for an unknown task", logging.TICKET_ID, msg.Ticket, logging.TASK_ID, msg.Task))
 		task = &Task{
 			Id:               msg.Task,
 			Status:           "open",
@@ -472,13 +674,16 @@ func (client *Client) GetTask(tasks map[TaskKey]*Task, msg *TaskRequest) *Task {
 		}
 		tasks[key] = task
 	}
-	log.Info(fmt.Sprintf("PREFER-SERVER-LANG: %v", msg.PreferServerProgLang))
+	log.Debugj(logging.Line("Serving task",
+		logging.TICKET_ID, msg.Ticket,
+		logging.TASK_ID, task.Id,
+		"prg_lang", task.ProgLang,
+		"requested_prg_lang", msg.ProgLang,
+		"human_lang", msg.HumanLang))
 	if msg.PreferServerProgLang {
-		log.Info(fmt.Sprintf("Updating task %s prog-lang form %s to %s", task.Id, task.ProgLang, msg.ProgLang))
 		task.ProgLang = msg.ProgLang
 	}
-	log.Info(fmt.Sprintf("Updating task %s prog-lang form %s to %s", task.Id, task.HumanLang, msg.HumanLang))
-	task.HumanLang = msg.HumanLang
+	task.SetHumanLang(msg.HumanLang)
 	task.SolutionTemplate = task.Templates[CUI_LANG_TO_MD[task.ProgLang]]
 	return task
 }
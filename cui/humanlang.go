@@ -0,0 +1,56 @@
+package cui
+
+import (
+	"encoding/json"
+	"github.com/maddyonline/problems"
+	"sort"
+)
+
+// DEFAULT_HUMAN_LANG is the language of problem statements, which tasks
+// fall back to when a translation is missing.
+const DEFAULT_HUMAN_LANG = "en"
+
+// descriptions renders the statement of a problem in every language of
+// DefaultHumanLangList it is available in.
+func descriptions(prob *problems.Problem, translations map[string]string) map[string]string {
+	descs := map[string]string{
+		DEFAULT_HUMAN_LANG: string(getDescFromMarkdown([]byte(prob.FullDesc))),
+	}
+	known := DefaultHumanLangList()
+	for lang, markdown := range translations {
+		if _, ok := known[lang]; ok {
+			descs[lang] = string(getDescFromMarkdown([]byte(markdown)))
+		}
+	}
+	return descs
+}
+
+// humanLangListJSON lists the languages of descs, the default first.
+func humanLangListJSON(descs map[string]string) string {
+	others := []string{}
+	for lang := range descs {
+		if lang != DEFAULT_HUMAN_LANG {
+			others = append(others, lang)
+		}
+	}
+	sort.Strings(others)
+	list, _ := json.Marshal(append([]string{DEFAULT_HUMAN_LANG}, others...))
+	return string(list)
+}
+
+// SetHumanLang shows the task's description in lang, or in English if it
+// was not translated to lang.
+func (task *Task) SetHumanLang(lang string) {
+	if len(task.Descriptions) == 0 {
+		// Tasks created before descriptions were localized.
+		task.HumanLang = lang
+		return
+	}
+	desc, ok := task.Descriptions[lang]
+	if !ok {
+		lang = DEFAULT_HUMAN_LANG
+		desc = task.Descriptions[lang]
+	}
+	task.HumanLang = lang
+	task.Description = desc
+}
@@ -0,0 +1,47 @@
+package cui
+
+import (
+	"github.com/maddyonline/problems"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLocalizedDescription(t *testing.T) {
+	client := &Client{
+		ProbsList: map[string]*problems.Problem{
+			"sum":  {Name: "sum", FullDesc: "Add *a* and *b*."},
+			"echo": {Name: "echo", FullDesc: "Print the input."},
+		},
+		Translations: map[string]map[string]string{
+			"sum": {"cn": "计算 *a* + *b*。", "fr": "Additionnez a et b."},
+		},
+		Mutex: &sync.Mutex{},
+	}
+	tasks := map[TaskKey]*Task{}
+	ticket, err := client.NewTicket(tasks, []string{"sum", "echo"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := tasks[TaskKey{ticket.Id, "sum"}]
+	if sum.HumanLangList != `["en","cn"]` || !strings.Contains(sum.Description, "<em>a</em>") {
+		t.Errorf("sum: languages %s, description %q", sum.HumanLangList, sum.Description)
+	}
+	if echo := tasks[TaskKey{ticket.Id, "echo"}]; echo.HumanLangList != `["en"]` {
+		t.Errorf("echo: languages %s", echo.HumanLangList)
+	}
+
+	for _, c := range []struct {
+		task, lang, want, desc string
+	}{
+		{"sum", "cn", "cn", "计算"},
+		{"sum", "fr", "en", "Add"},
+		{"echo", "cn", "en", "Print"},
+		{"sum", "en", "en", "Add"},
+	} {
+		task := client.GetTask(tasks, &TaskRequest{Task: c.task, Ticket: ticket.Id, HumanLang: c.lang})
+		if task.HumanLang != c.want || !strings.Contains(task.Description, c.desc) {
+			t.Errorf("%s in %s: got %s, %q", c.task, c.lang, task.HumanLang, task.Description)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package cui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Message identifies a string the server shows to candidates.
+type Message string
+
+const (
+	MSG_OK              Message = "ok"
+	MSG_COMPILED        Message = "compiled"
+	MSG_NOT_COMPILED    Message = "not_compiled"
+	MSG_NOT_RUN         Message = "not_run"
+	MSG_EVALUATING      Message = "evaluating"
+	MSG_WENT_WRONG      Message = "went_wrong"
+	MSG_WENT_WRONG_WITH Message = "went_wrong_with"
+	MSG_NOT_FOUND       Message = "not_found"
+	MSG_INVALID_SESSION Message = "invalid_session"
+	MSG_NO_SESSION      Message = "no_session"
+	MSG_SESSION_EXPIRED Message = "session_expired"
+	MSG_TICKET_CLOSED   Message = "ticket_closed"
+	MSG_OTHER_BROWSER   Message = "other_browser"
+	MSG_RESTARTING      Message = "restarting"
+	MSG_PROG_LANG       Message = "prog_lang_not_allowed"
+	MSG_NO_INVITE       Message = "no_invite"
+	MSG_INVITE_USED     Message = "invite_used"
+	MSG_INVITE_EXPIRED  Message = "invite_expired"
+	MSG_INVITE_EARLY    Message = "invite_not_yet_valid"
+	MSG_INVALID_CSRF    Message = "invalid_csrf"
+	MSG_NO_LSP          Message = "no_lsp"
+	MSG_NO_LSP_FOR      Message = "no_lsp_for"
+	MSG_LSP_BUSY        Message = "lsp_busy"
+	MSG_WELCOME         Message = "welcome"
+	MSG_WELCOME_NAME    Message = "welcome_name"
+	MSG_SORRY           Message = "sorry"
+	MSG_INVITE_TASKS    Message = "invite_tasks"
+	MSG_INVITE_TIMER    Message = "invite_timer"
+	MSG_INVITE_LANGS    Message = "invite_langs"
+	MSG_INVITE_ANY_LANG Message = "invite_any_lang"
+	MSG_INVITE_BUTTONS  Message = "invite_buttons"
+	MSG_INVITE_ONCE     Message = "invite_once"
+	MSG_START           Message = "start"
+)
+
+// Messages are the catalogs of every language of DefaultHumanLangList.
+// Messages taking arguments are fmt formats.
+var Messages = map[string]map[Message]string{
+	"en": {
+		MSG_OK:              "OK",
+		MSG_COMPILED:        "The solution compiled flawlessly.",
+		MSG_NOT_COMPILED:    "Compilation failed:\n%s",
+		MSG_NOT_RUN:         "The solution was not run because it does not compile.",
+		MSG_EVALUATING:      "We are still evaluating the solution",
+		MSG_WENT_WRONG:      "Something went wrong",
+		MSG_WENT_WRONG_WITH: "Something went wrong: %v",
+		MSG_NOT_FOUND:       "Not Found",
+		MSG_INVALID_SESSION: "Attempt to start an invalid session",
+		MSG_NO_SESSION:      "No valid session found",
+		MSG_SESSION_EXPIRED: "Session Expired",
+		MSG_TICKET_CLOSED:   "Ticket is closed",
+		MSG_OTHER_BROWSER:   "This test was started in another browser",
+		MSG_RESTARTING:      "The server is restarting, please submit again in a minute",
+		MSG_PROG_LANG:       "%s is not allowed in this test",
+		MSG_NO_INVITE:       "No such invitation",
+		MSG_INVITE_USED:     "This invitation has already been used",
+		MSG_INVITE_EXPIRED:  "This invitation has expired",
+		MSG_INVITE_EARLY:    "This invitation is not valid yet",
+		MSG_INVALID_CSRF:    "Invalid CSRF token",
+		MSG_NO_LSP:          "Language servers are not enabled for this ticket",
+		MSG_NO_LSP_FOR:      "No language server for %s",
+		MSG_LSP_BUSY:        "Too many language servers are running, please try again in a minute",
+		MSG_WELCOME:         "Welcome",
+		MSG_WELCOME_NAME:    "Welcome, %s",
+		MSG_SORRY:           "Sorry",
+		MSG_INVITE_TASKS:    "You are about to start a programming test with %d task(s). You will have %d minutes to solve them.",
+		MSG_INVITE_TIMER:    "Start opens the test. The timer starts when you begin the test on the next page, and cannot be paused.",
+		MSG_INVITE_LANGS:    "You may write your solutions in %s.",
+		MSG_INVITE_ANY_LANG: "You may write your solutions in any of the offered languages.",
+		MSG_INVITE_BUTTONS:  "Use RUN to check your solution against the example tests, and SUBMIT THIS TASK when you are done.",
+		MSG_INVITE_ONCE:     "This link works only once and the test stays tied to this browser. Do not close it or switch computers.",
+		MSG_START:           "Start",
+	},
+	"cn": {
+		MSG_OK:              "通过",
+		MSG_COMPILED:        "程序编译成功。",
+		MSG_NOT_COMPILED:    "编译失败：\n%s",
+		MSG_NOT_RUN:         "程序未能编译，因此没有运行。",
+		MSG_EVALUATING:      "我们仍在评测您的程序",
+		MSG_WENT_WRONG:      "出错了",
+		MSG_WENT_WRONG_WITH: "出错了：%v",
+		MSG_NOT_FOUND:       "未找到",
+		MSG_INVALID_SESSION: "无法开始无效的会话",
+		MSG_NO_SESSION:      "未找到有效的会话",
+		MSG_SESSION_EXPIRED: "会话已过期",
+		MSG_TICKET_CLOSED:   "本次测试已结束",
+		MSG_OTHER_BROWSER:   "本次测试已在另一个浏览器中开始",
+		MSG_RESTARTING:      "服务器正在重启，请稍后再提交",
+		MSG_PROG_LANG:       "本次测试不允许使用 %s",
+		MSG_NO_INVITE:       "未找到该邀请",
+		MSG_INVITE_USED:     "该邀请已被使用",
+		MSG_INVITE_EXPIRED:  "该邀请已过期",
+		MSG_INVITE_EARLY:    "该邀请尚未生效",
+		MSG_INVALID_CSRF:    "无效的 CSRF 令牌",
+		MSG_NO_LSP:          "本次测试未启用语言服务器",
+		MSG_NO_LSP_FOR:      "没有 %s 的语言服务器",
+		MSG_LSP_BUSY:        "正在运行的语言服务器过多，请稍后再试",
+		MSG_WELCOME:         "欢迎",
+		MSG_WELCOME_NAME:    "%s，欢迎",
+		MSG_SORRY:           "抱歉",
+		MSG_INVITE_TASKS:    "您即将开始一场包含 %d 道题的编程测试，共有 %d 分钟的作答时间。",
+		MSG_INVITE_TIMER:    "点击“开始”将打开测试。计时在您于下一页开始测试时才开始，且无法暂停。",
+		MSG_INVITE_LANGS:    "您可以使用 %s 编写程序。",
+		MSG_INVITE_ANY_LANG: "您可以使用所提供的任意语言编写程序。",
+		MSG_INVITE_BUTTONS:  "使用 RUN 按示例测试检查您的程序，完成后点击 SUBMIT THIS TASK。",
+		MSG_INVITE_ONCE:     "此链接只能使用一次，测试将与此浏览器绑定。请不要关闭浏览器或更换电脑。",
+		MSG_START:           "开始",
+	},
+}
+
+// T returns msg in the human language lang, falling back to English.
+func T(lang string, msg Message, args ...interface{}) string {
+	s, ok := Messages[lang][msg]
+	if !ok {
+		s = Messages[DEFAULT_HUMAN_LANG][msg]
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(s, args...)
+	}
+	return s
+}
+
+// acceptLanguages maps the languages of an Accept-Language header to the
+// human languages of the interface.
+var acceptLanguages = map[string]string{"en": "en", "zh": "cn"}
+
+// MatchHumanLang picks the human language for an Accept-Language header,
+// for candidates whose ticket does not tell.
+func MatchHumanLang(acceptLanguage string) string {
+	for _, tag := range strings.Split(acceptLanguage, ",") {
+		tag = strings.ToLower(strings.TrimSpace(strings.SplitN(tag, ";", 2)[0]))
+		if lang, ok := acceptLanguages[strings.SplitN(tag, "-", 2)[0]]; ok {
+			return lang
+		}
+	}
+	return DEFAULT_HUMAN_LANG
+}
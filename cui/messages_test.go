@@ -0,0 +1,59 @@
+package cui
+
+import (
+	"regexp"
+	"testing"
+)
+
+var verb = regexp.MustCompile(`%[a-z]`)
+
+func TestMessagesTranslated(t *testing.T) {
+	for lang := range DefaultHumanLangList() {
+		catalog, ok := Messages[lang]
+		if !ok {
+			t.Errorf("no catalog for %s", lang)
+			continue
+		}
+		for msg, en := range Messages[DEFAULT_HUMAN_LANG] {
+			s, ok := catalog[msg]
+			if !ok || s == "" {
+				t.Errorf("%s: %s is not translated", lang, msg)
+				continue
+			}
+			if got, want := verb.FindAllString(s, -1), verb.FindAllString(en, -1); len(got) != len(want) {
+				t.Errorf("%s: %s takes %v, English takes %v", lang, msg, got, want)
+			}
+		}
+		for msg := range catalog {
+			if _, ok := Messages[DEFAULT_HUMAN_LANG][msg]; !ok {
+				t.Errorf("%s: %s is not an English message", lang, msg)
+			}
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T("cn", MSG_WENT_WRONG_WITH, "boom"); got != "出错了：boom" {
+		t.Errorf("got %q", got)
+	}
+	if got := T("fr", MSG_TICKET_CLOSED); got != "Ticket is closed" {
+		t.Errorf("no fallback to English: %q", got)
+	}
+	if got := LaterReply("k", "cn").Message; got != Messages["cn"][MSG_EVALUATING] {
+		t.Errorf("LaterReply: %q", got)
+	}
+}
+
+func TestMatchHumanLang(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                        "en",
+		"zh-CN,zh;q=0.9,en;q=0.8": "cn",
+		"fr-FR, en-US;q=0.5":      "en",
+		"de":                      "en",
+		"en-GB,zh-TW;q=0.9":       "en",
+	} {
+		if got := MatchHumanLang(header); got != want {
+			t.Errorf("MatchHumanLang(%q) = %s, want %s", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package cui
+
+import (
+	"encoding/json"
+	"github.com/maddyonline/g2/compile"
+)
+
+// The candidate interface speaks XML. Clients sending "Accept:
+// application/json" get the documents below instead; their field names
+// follow the XML elements and are part of the protocol, so they must not
+// change.
+
+// TicketJSON is what GET /cui/<ticket> answers JSON clients with instead
+// of the candidate's page.
+type TicketJSON struct {
+	TicketId  string   `json:"ticket_id"`
+	CSRFToken string   `json:"csrf_token"`
+	Options   *Options `json:"options"`
+}
+
+type TaskJSON struct {
+	Id               string   `json:"id"`
+	Status           string   `json:"task_status"`
+	Description      string   `json:"task_description"`
+	Type             string   `json:"task_type"`
+	SolutionTemplate string   `json:"solution_template"`
+	CurrentSolution  string   `json:"current_solution"`
+	ExampleInput     string   `json:"example_input"`
+	ProgLangList     []string `json:"prg_lang_list"`
+	HumanLangList    []string `json:"human_lang_list"`
+	ProgLang         string   `json:"prg_lang"`
+	HumanLang        string   `json:"human_lang"`
+}
+
+// stringList decodes the JSON lists the XML documents embed as text.
+func stringList(s string) []string {
+	list := []string{}
+	if err := json.Unmarshal([]byte(s), &list); err != nil || list == nil {
+		return []string{}
+	}
+	return list
+}
+
+func (t *Task) ToJSON() *TaskJSON {
+	return &TaskJSON{
+		Id:               t.Id,
+		Status:           t.Status,
+		Description:      t.Description,
+		Type:             t.Type,
+		SolutionTemplate: t.SolutionTemplate,
+		CurrentSolution:  t.CurrentSolution,
+		ExampleInput:     t.ExampleInput,
+		ProgLangList:     stringList(t.ProgLangList),
+		HumanLangList:    stringList(t.HumanLangList),
+		ProgLang:         t.ProgLang,
+		HumanLang:        t.HumanLang,
+	}
+}
+
+type StatusJSON struct {
+	OK          bool                 `json:"ok"`
+	Message     string               `json:"message"`
+	Diagnostics []compile.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+type MainStatusJSON struct {
+	Compile   StatusJSON `json:"compile"`
+	Example   StatusJSON `json:"example"`
+	TestData0 StatusJSON `json:"test_data0"`
+	TestData1 StatusJSON `json:"test_data1"`
+	TestData2 StatusJSON `json:"test_data2"`
+	TestData3 StatusJSON `json:"test_data3"`
+	TestData4 StatusJSON `json:"test_data4"`
+}
+
+type VerifyStatusJSON struct {
+	Result  string         `json:"result"`
+	Message string         `json:"message"`
+	Id      string         `json:"id"`
+	Delay   int            `json:"delay"`
+	Extra   MainStatusJSON `json:"extra"`
+}
+
+func (s Status) ToJSON() StatusJSON {
+	return StatusJSON{s.OK != 0, s.Message, s.Diagnostics}
+}
+
+func (v *VerifyStatus) ToJSON() *VerifyStatusJSON {
+	return &VerifyStatusJSON{
+		Result:  v.Result,
+		Message: v.Message,
+		Id:      v.Id,
+		Delay:   v.Delay,
+		Extra: MainStatusJSON{
+			Compile:   v.Extra.Compile.ToJSON(),
+			Example:   v.Extra.Example.ToJSON(),
+			TestData0: v.Extra.TestData0.ToJSON(),
+			TestData1: v.Extra.TestData1.ToJSON(),
+			TestData2: v.Extra.TestData2.ToJSON(),
+			TestData3: v.Extra.TestData3.ToJSON(),
+			TestData4: v.Extra.TestData4.ToJSON(),
+		},
+	}
+}
+
+type ClockResponseJSON struct {
+	Result       string `json:"result"`
+	NewTimeLimit int    `json:"new_timelimit"`
+}
+
+func (r *ClockResponse) ToJSON() *ClockResponseJSON {
+	return &ClockResponseJSON{r.Result, r.NewTimeLimit}
+}
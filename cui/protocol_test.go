@@ -0,0 +1,80 @@
+package cui
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"github.com/maddyonline/g2/compile"
+	"testing"
+)
+
+// These lock the wire format of the candidate interface in both encodings;
+// a change here breaks every client.
+func TestProtocolEncodings(t *testing.T) {
+	task := &Task{
+		Id:               "sum",
+		Status:           "open",
+		Description:      "<p>Add</p>",
+		Type:             "algo",
+		SolutionTemplate: "tmpl",
+		CurrentSolution:  "sol",
+		ProgLangList:     `["cpp","py3"]`,
+		HumanLangList:    `["en","cn"]`,
+		ProgLang:         "cpp",
+		HumanLang:        "en",
+		Templates:        map[string]string{"cpp": "x"},
+		ProblemVersion:   "v1",
+	}
+	verify := defaultVerifyStatus("en")
+	verify.Id = "ab12"
+	verify.Extra.Example = Status{0, "wrong", nil}
+	broken := &VerifyStatus{Result: "OK", Id: "cd34"}
+	broken.Extra.Compile = Status{0, "bad", []compile.Diagnostic{{2, 5, compile.ERROR, "expected ';'"}}}
+	clock := &ClockResponse{Result: "OK", NewTimeLimit: 42}
+
+	for _, c := range []struct {
+		name      string
+		doc       interface{}
+		jsonDoc   interface{}
+		xml, json string
+	}{
+		{
+			"task", task, task.ToJSON(),
+			`<response><id>sum</id><task_status>open</task_status><task_description>&lt;p&gt;Add&lt;/p&gt;</task_description><task_type>algo</task_type><solution_template>tmpl</solution_template><current_solution>sol</current_solution><example_input></example_input><prg_lang_list>[&#34;cpp&#34;,&#34;py3&#34;]</prg_lang_list><human_lang_list>[&#34;en&#34;,&#34;cn&#34;]</human_lang_list><prg_lang>cpp</prg_lang><human_lang>en</human_lang></response>`,
+			`{"id":"sum","task_status":"open","task_description":"\u003cp\u003eAdd\u003c/p\u003e","task_type":"algo","solution_template":"tmpl","current_solution":"sol","example_input":"","prg_lang_list":["cpp","py3"],"human_lang_list":["en","cn"],"prg_lang":"cpp","human_lang":"en"}`,
+		},
+		{
+			"verify", verify, verify.ToJSON(),
+			`<response><result>OK</result><message></message><id>ab12</id><delay>0</delay><extra><compile><ok>1</ok><message>The solution compiled flawlessly.</message></compile><example><ok>0</ok><message>wrong</message></example><test_data0><ok>1</ok><message>OK</message></test_data0><test_data1><ok>1</ok><message>OK</message></test_data1><test_data2><ok>1</ok><message>OK</message></test_data2><test_data3><ok>1</ok><message>OK</message></test_data3><test_data4><ok>1</ok><message>OK</message></test_data4></extra></response>`,
+			`{"result":"OK","message":"","id":"ab12","delay":0,"extra":{"compile":{"ok":true,"message":"The solution compiled flawlessly."},"example":{"ok":false,"message":"wrong"},"test_data0":{"ok":true,"message":"OK"},"test_data1":{"ok":true,"message":"OK"},"test_data2":{"ok":true,"message":"OK"},"test_data3":{"ok":true,"message":"OK"},"test_data4":{"ok":true,"message":"OK"}}}`,
+		},
+		{
+			"compile error", broken, broken.ToJSON(),
+			`<response><result>OK</result><message></message><id>cd34</id><delay>0</delay><extra><compile><ok>0</ok><message>bad</message><diagnostic><line>2</line><column>5</column><severity>error</severity><message>expected &#39;;&#39;</message></diagnostic></compile><example><ok>0</ok><message></message></example><test_data0><ok>0</ok><message></message></test_data0><test_data1><ok>0</ok><message></message></test_data1><test_data2><ok>0</ok><message></message></test_data2><test_data3><ok>0</ok><message></message></test_data3><test_data4><ok>0</ok><message></message></test_data4></extra></response>`,
+			`{"result":"OK","message":"","id":"cd34","delay":0,"extra":{"compile":{"ok":false,"message":"bad","diagnostics":[{"line":2,"column":5,"severity":"error","message":"expected ';'"}]},"example":{"ok":false,"message":""},"test_data0":{"ok":false,"message":""},"test_data1":{"ok":false,"message":""},"test_data2":{"ok":false,"message":""},"test_data3":{"ok":false,"message":""},"test_data4":{"ok":false,"message":""}}}`,
+		},
+		{
+			"clock", clock, clock.ToJSON(),
+			`<response><result>OK</result><new_timelimit>42</new_timelimit></response>`,
+			`{"result":"OK","new_timelimit":42}`,
+		},
+	} {
+		b, err := xml.Marshal(c.doc)
+		if err != nil || string(b) != c.xml {
+			t.Errorf("%s XML:\n got %s (%v)\nwant %s", c.name, b, err, c.xml)
+		}
+		b, err = json.Marshal(c.jsonDoc)
+		if err != nil || string(b) != c.json {
+			t.Errorf("%s JSON:\n got %s (%v)\nwant %s", c.name, b, err, c.json)
+		}
+	}
+}
+
+func TestTaskJSONLists(t *testing.T) {
+	// Tasks served before their lists were filled in.
+	b, _ := json.Marshal((&Task{}).ToJSON())
+	var got map[string]interface{}
+	json.Unmarshal(b, &got)
+	if got["prg_lang_list"] == nil || got["human_lang_list"] == nil {
+		t.Errorf("lists must encode as arrays: %s", b)
+	}
+}
@@ -0,0 +1,163 @@
+package cui
+
+import (
+	"time"
+)
+
+// TestVerdict is the outcome of one check within a verdict.
+type TestVerdict struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+}
+
+// Passed reports whether the solution compiled and passed every test.
+func (v *VerifyStatus) Passed() bool {
+	if v.Result != "OK" {
+		return false
+	}
+	for _, t := range v.Tests() {
+		if !t.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// Tests lists the checks of the verdict one by one.
+func (v *VerifyStatus) Tests() []TestVerdict {
+	test := func(name string, s Status) TestVerdict {
+		return TestVerdict{name, s.OK == 1, s.Message}
+	}
+	return []TestVerdict{
+		test("compile", v.Extra.Compile),
+		test("example", v.Extra.Example),
+		test("test_data0", v.Extra.TestData0),
+		test("test_data1", v.Extra.TestData1),
+		test("test_data2", v.Extra.TestData2),
+		test("test_data3", v.Extra.TestData3),
+		test("test_data4", v.Extra.TestData4),
+	}
+}
+
+type ResultStatus string
+
+const (
+	PASSED     ResultStatus = "passed"
+	FAILED     ResultStatus = "failed"
+	PENDING    ResultStatus = "pending"
+	NOT_JUDGED ResultStatus = "not_judged"
+)
+
+// TaskResult is how a candidate did on one task: the final submission if
+// there is one, otherwise the last judged one. Verifying only runs the
+// example, so it does not count.
+type TaskResult struct {
+	TaskId    string        `json:"task_id"`
+	ProgLang  string        `json:"prg_lang"`
+	Solution  string        `json:"solution"`
+	Submitted time.Time     `json:"submitted"`
+	Status    ResultStatus  `json:"status"`
+	Verdict   *VerifyStatus `json:"verdict"`
+	Score     int           `json:"score"`
+	TimeSpent time.Duration `json:"time_spent"`
+}
+
+// Pending reports whether the task was judged and awaits its verdict.
+func (res *TaskResult) Pending() bool {
+	return res.Status == PENDING
+}
+
+// Results summarises every task of the session. tasks provides the current
+// solution of tasks that were never judged.
+func (s *Session) Results(tasks []*Task) []*TaskResult {
+	spent := s.TimeSpent()
+	s.Lock()
+	defer s.Unlock()
+	results := []*TaskResult{}
+	for _, task := range tasks {
+		res := &TaskResult{TaskId: task.Id, ProgLang: task.ProgLang, Solution: task.CurrentSolution,
+			Status: NOT_JUDGED, TimeSpent: spent[task.Id]}
+		var last *Submission
+		for _, sub := range s.Submissions {
+			if sub.TaskId != task.Id || sub.Mode == VERIFY.String() {
+				continue
+			}
+			if last == nil || last.Mode != FINAL.String() || sub.Mode == FINAL.String() {
+				last = sub
+			}
+		}
+		if last != nil {
+			res.ProgLang = last.ProgLang
+			res.Solution = last.Solution
+			res.Submitted = last.Time
+			res.Verdict = last.Verdict
+			switch {
+			case last.Verdict == nil:
+				res.Status = PENDING
+			case last.Verdict.Passed():
+				res.Status, res.Score = PASSED, 100
+			default:
+				res.Status = FAILED
+			}
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// Pending reports whether any of the results awaits its verdict, so their
+// Score may still change.
+func Pending(results []*TaskResult) bool {
+	for _, res := range results {
+		if res.Pending() {
+			return true
+		}
+	}
+	return false
+}
+
+// Score is the average score of the tasks, out of 100. Tasks awaiting their
+// verdict count as 0 until it is known.
+func Score(results []*TaskResult) int {
+	if len(results) == 0 {
+		return 0
+	}
+	total := 0
+	for _, res := range results {
+		total += res.Score
+	}
+	return total / len(results)
+}
+
+// TimeSpent estimates the time spent on each task: the time between two
+// consecutive submissions (or the start of the session and the first one) is
+// counted towards the task of the later submission.
+func (s *Session) TimeSpent() map[string]time.Duration {
+	s.Lock()
+	defer s.Unlock()
+	spent := map[string]time.Duration{}
+	last := s.StartTime
+	for _, sub := range s.Submissions {
+		if !last.IsZero() && sub.Time.After(last) {
+			spent[sub.TaskId] += sub.Time.Sub(last) / time.Second * time.Second
+		}
+		last = sub.Time
+	}
+	return spent
+}
+
+// TimeUsed is how long the candidate has been working, up to the end of
+// the session.
+func (s *Session) TimeUsed() time.Duration {
+	s.Lock()
+	defer s.Unlock()
+	if s.StartTime.IsZero() {
+		return 0
+	}
+	end := s.EndTime
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return end.Sub(s.StartTime) / time.Second * time.Second
+}
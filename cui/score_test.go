@@ -0,0 +1,109 @@
+package cui
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func verdict(passed bool) *VerifyStatus {
+	v := defaultVerifyStatus("en")
+	if !passed {
+		v.Extra.TestData2 = Status{0, "wrong answer", nil}
+	}
+	return v
+}
+
+func TestResults(t *testing.T) {
+	start := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	session := NewSession(&Ticket{Id: "t1"}, "", 3600)
+	session.StartTime = start
+	at := func(min int) time.Time { return start.Add(time.Duration(min) * time.Minute) }
+	session.Submissions = []*Submission{
+		// A verified solution is not judged.
+		{TaskId: "verified", Mode: "VERIFY", ProgLang: "py3", Solution: "v", Time: at(1), Verdict: verdict(true)},
+		// The final submission counts, even if judging did better.
+		{TaskId: "final", Mode: "JUDGE", ProgLang: "cpp", Solution: "j", Time: at(2), Verdict: verdict(true)},
+		{TaskId: "final", Mode: "FINAL", ProgLang: "cpp", Solution: "f", Time: at(3), Verdict: verdict(false)},
+		{TaskId: "final", Mode: "VERIFY", ProgLang: "cpp", Solution: "v", Time: at(4), Verdict: verdict(true)},
+		// Without a final submission, the last judged one counts.
+		{TaskId: "judged", Mode: "JUDGE", ProgLang: "go", Solution: "j1", Time: at(5), Verdict: verdict(false)},
+		{TaskId: "judged", Mode: "JUDGE", ProgLang: "go", Solution: "j2", Time: at(6), Verdict: verdict(true)},
+		{TaskId: "pending", Mode: "FINAL", ProgLang: "c", Solution: "p", Time: at(7)},
+	}
+	tasks := []*Task{
+		{Id: "verified", ProgLang: "py3", CurrentSolution: "current"},
+		{Id: "final"},
+		{Id: "judged"},
+		{Id: "pending"},
+	}
+	results := session.Results(tasks)
+	type summary struct {
+		Status   ResultStatus
+		Score    int
+		Solution string
+	}
+	got := []summary{}
+	for _, res := range results {
+		got = append(got, summary{res.Status, res.Score, res.Solution})
+	}
+	want := []summary{
+		{NOT_JUDGED, 0, "current"},
+		{FAILED, 0, "f"},
+		{PASSED, 100, "j2"},
+		{PENDING, 0, "p"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Results:\ngot  %+v\nwant %+v", got, want)
+	}
+	if results[0].Verdict != nil {
+		t.Errorf("verified task has a verdict: %+v", results[0].Verdict)
+	}
+	if !Pending(results) || Pending(results[:3]) {
+		t.Error("Pending wrong")
+	}
+	if got := Score(results); got != 25 {
+		t.Errorf("Score = %d, want 25", got)
+	}
+	if got := Score(nil); got != 0 {
+		t.Errorf("Score(nil) = %d", got)
+	}
+}
+
+func TestTests(t *testing.T) {
+	v := verdict(false)
+	if v.Passed() {
+		t.Error("passed with a failed test")
+	}
+	tests := v.Tests()
+	if len(tests) != 7 {
+		t.Fatalf("%d tests, want compile, example and 5 test data", len(tests))
+	}
+	for _, test := range tests {
+		if test.OK != (test.Name != "test_data2") {
+			t.Errorf("%+v", test)
+		}
+	}
+	if !verdict(true).Passed() {
+		t.Error("failed with every test passed")
+	}
+}
+
+func TestTimeSpent(t *testing.T) {
+	start := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	session := NewSession(&Ticket{Id: "t1"}, "", 3600)
+	session.StartTime = start
+	session.Submissions = []*Submission{
+		{TaskId: "a", Time: start.Add(10 * time.Minute)},
+		{TaskId: "b", Time: start.Add(25*time.Minute + 500*time.Millisecond)},
+		{TaskId: "a", Time: start.Add(30 * time.Minute)},
+	}
+	want := map[string]time.Duration{
+		// Partial seconds are dropped.
+		"a": 14*time.Minute + 59*time.Second,
+		"b": 15 * time.Minute,
+	}
+	if got := session.TimeSpent(); !reflect.DeepEqual(got, want) {
+		t.Errorf("TimeSpent = %v, want %v", got, want)
+	}
+}
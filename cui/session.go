@@ -0,0 +1,338 @@
+package cui
+
+import (
+	"sync"
+	"time"
+)
+
+type TicketStatus string
+
+const (
+	CREATED   TicketStatus = "created"
+	STARTED   TicketStatus = "started"
+	FINISHED  TicketStatus = "finished"
+	TIMEDOUT  TicketStatus = "timed_out"
+	CANCELLED TicketStatus = "cancelled"
+)
+
+// Closed reports whether a ticket in this status can no longer be worked on.
+func (s TicketStatus) Closed() bool {
+	return s == FINISHED || s == TIMEDOUT || s == CANCELLED
+}
+
+type EventKind string
+
+const (
+	BOUND         EventKind = "bound"
+	IP_CHANGED    EventKind = "ip_changed"
+	AGENT_CHANGED EventKind = "user_agent_changed"
+	REJECTED      EventKind = "rejected"
+	UNBOUND       EventKind = "unbound"
+)
+
+// MAX_REJECTED is how many browsers a ticket's rejected requests are told
+// apart for; the requests of any more count towards the last of them.
+const MAX_REJECTED = 10
+
+// Event records which machine the candidate was working from, so reviewers
+// can tell whether a ticket was opened from several places. Rejected
+// requests from the same browser make up one event, with their Count and
+// the time the last one was Seen. Unbinding records who did it By.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Kind      EventKind `json:"kind"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Count     int       `json:"count,omitempty"`
+	Seen      time.Time `json:"seen"`
+	By        string    `json:"by,omitempty"`
+}
+
+// Submission is a single verify/judge/final request made by the candidate,
+// together with its verdict once known.
+type Submission struct {
+	Id       string        `json:"id"`
+	TaskId   string        `json:"task_id"`
+	Mode     string        `json:"mode"`
+	ProgLang string        `json:"prg_lang"`
+	Solution string        `json:"solution"`
+	Time     time.Time     `json:"time"`
+	Verdict  *VerifyStatus `json:"verdict,omitempty"`
+}
+
+type Session struct {
+	Ticket      *Ticket
+	Candidate   string
+	Invite      string
+	Status      TicketStatus
+	StartTime   time.Time
+	EndTime     time.Time
+	Created     time.Time
+	Started     bool
+	TimeLimit   int
+	Submissions []*Submission
+	Bound       bool
+	Events      []*Event
+	sync.Mutex  `json:"-"`
+}
+
+func NewSession(ticket *Ticket, candidate string, timeLimit int) *Session {
+	return &Session{
+		Ticket:    ticket,
+		Candidate: candidate,
+		Status:    CREATED,
+		Created:   time.Now(),
+		TimeLimit: timeLimit,
+	}
+}
+
+// Start records the moment the candidate started working; the clock runs
+// from here.
+func (s *Session) Start() {
+	s.Lock()
+	defer s.Unlock()
+	if s.Status != CREATED {
+		return
+	}
+	s.Status = STARTED
+	s.StartTime = time.Now()
+}
+
+// HumanLang is the language the candidate last read a task in.
+func (s *Session) HumanLang() string {
+	s.Lock()
+	defer s.Unlock()
+	if s.Ticket.Options == nil {
+		return ""
+	}
+	return s.Ticket.Options.CurrentHumanLang
+}
+
+// SetHumanLang records the language the candidate switched to.
+func (s *Session) SetHumanLang(lang string) {
+	s.Lock()
+	defer s.Unlock()
+	if s.Ticket.Options != nil {
+		s.Ticket.Options.CurrentHumanLang = lang
+	}
+}
+
+// AllowsProgLang reports whether the ticket lets the candidate write in
+// progLang.
+func (s *Session) AllowsProgLang(progLang string) bool {
+	s.Lock()
+	defer s.Unlock()
+	if s.Ticket.Options == nil {
+		return false
+	}
+	_, ok := s.Ticket.Options.ProgLangList[progLang]
+	return ok
+}
+
+// Open records that the candidate opened the ticket. Tickets without a
+// candidate, such as practice tickets, must be opened within wait of being
+// created; Open returns false if they were not.
+func (s *Session) Open(wait time.Duration) bool {
+	s.Lock()
+	defer s.Unlock()
+	if s.Started {
+		return true
+	}
+	if s.Candidate == "" && time.Since(s.Created) > wait {
+		return false
+	}
+	s.Started = true
+	return true
+}
+
+// Close moves the session to a closed status unless it already is closed.
+func (s *Session) Close(status TicketStatus) bool {
+	s.Lock()
+	defer s.Unlock()
+	s.refresh()
+	if s.Status.Closed() {
+		return false
+	}
+	s.Status = status
+	s.EndTime = time.Now()
+	return true
+}
+
+// Refresh times out a started session whose time limit has passed and
+// returns the current status.
+func (s *Session) Refresh() TicketStatus {
+	s.Lock()
+	defer s.Unlock()
+	s.refresh()
+	return s.Status
+}
+
+func (s *Session) refresh() {
+	if s.Status != STARTED {
+		return
+	}
+	deadline := s.deadline()
+	if time.Now().After(deadline) {
+		s.Status = TIMEDOUT
+		s.EndTime = deadline
+	}
+}
+
+// Deadline is when the time of a started session runs out.
+func (s *Session) Deadline() time.Time {
+	s.Lock()
+	defer s.Unlock()
+	return s.deadline()
+}
+
+func (s *Session) deadline() time.Time {
+	return s.StartTime.Add(time.Duration(s.TimeLimit) * time.Second)
+}
+
+func (s *Session) addEvent(kind EventKind, ip, userAgent string) *Event {
+	ev := &Event{Time: time.Now(), Kind: kind, IP: ip, UserAgent: userAgent}
+	s.Events = append(s.Events, ev)
+	return ev
+}
+
+func (s *Session) last() *Event {
+	for i := len(s.Events) - 1; i >= 0; i-- {
+		if s.Events[i].Kind != REJECTED {
+			return s.Events[i]
+		}
+	}
+	return nil
+}
+
+// Bind ties the session to the browser it is first opened in. It returns
+// false if the session already was bound.
+func (s *Session) Bind(ip, userAgent string) bool {
+	s.Lock()
+	defer s.Unlock()
+	if s.Bound {
+		return false
+	}
+	s.Bound = true
+	s.addEvent(BOUND, ip, userAgent)
+	return true
+}
+
+// Unbind lets the next browser to open the session bind it, for a candidate
+// who lost the cookie of the bound one. It returns false if the session was
+// not bound.
+func (s *Session) Unbind(by string) bool {
+	s.Lock()
+	defer s.Unlock()
+	if !s.Bound {
+		return false
+	}
+	s.Bound = false
+	s.addEvent(UNBOUND, "", "").By = by
+	return true
+}
+
+// Seen notes a request from the bound browser and returns an event if it
+// came from a different address or user agent than the previous one.
+func (s *Session) Seen(ip, userAgent string) *Event {
+	s.Lock()
+	defer s.Unlock()
+	last := s.last()
+	switch {
+	case last == nil:
+		return s.addEvent(BOUND, ip, userAgent)
+	case last.IP != ip:
+		return s.addEvent(IP_CHANGED, ip, userAgent)
+	case last.UserAgent != userAgent:
+		return s.addEvent(AGENT_CHANGED, ip, userAgent)
+	}
+	return nil
+}
+
+// Reject notes a request for this ticket from a browser it is not bound to.
+// It returns a copy of the event counting the request, and whether the
+// browser had not been rejected before.
+func (s *Session) Reject(ip, userAgent string) (*Event, bool) {
+	s.Lock()
+	defer s.Unlock()
+	ev, first := s.reject(ip, userAgent)
+	copied := *ev
+	return &copied, first
+}
+
+func (s *Session) reject(ip, userAgent string) (*Event, bool) {
+	var last *Event
+	rejected := 0
+	for _, ev := range s.Events {
+		if ev.Kind != REJECTED {
+			continue
+		}
+		if ev.IP == ip && ev.UserAgent == userAgent {
+			ev.Count++
+			ev.Seen = time.Now()
+			return ev, false
+		}
+		rejected++
+		last = ev
+	}
+	if rejected >= MAX_REJECTED {
+		last.Count++
+		last.Seen = time.Now()
+		return last, false
+	}
+	ev := s.addEvent(REJECTED, ip, userAgent)
+	ev.Count, ev.Seen = 1, ev.Time
+	return ev, true
+}
+
+// Shared reports whether the ticket looks like it was used from more than
+// one machine.
+func (s *Session) Shared() bool {
+	s.Lock()
+	defer s.Unlock()
+	for _, ev := range s.Events {
+		if ev.Kind != BOUND {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Session) AddSubmission(id string, task *Task, mode Mode) *Submission {
+	sub := &Submission{
+		Id:       id,
+		TaskId:   task.Id,
+		Mode:     mode.String(),
+		ProgLang: task.ProgLang,
+		Solution: task.CurrentSolution,
+		Time:     time.Now(),
+	}
+	s.Lock()
+	s.Submissions = append(s.Submissions, sub)
+	s.Unlock()
+	return sub
+}
+
+func (s *Session) SetVerdict(sub *Submission, verdict *VerifyStatus) {
+	s.Lock()
+	sub.Verdict = verdict
+	s.Unlock()
+}
+
+// History returns a copy of the submissions made so far.
+func (s *Session) History() []*Submission {
+	s.Lock()
+	defer s.Unlock()
+	return append([]*Submission{}, s.Submissions...)
+}
+
+// EventLog returns a copy of the events recorded so far.
+func (s *Session) EventLog() []*Event {
+	s.Lock()
+	defer s.Unlock()
+	list := []*Event{}
+	for _, ev := range s.Events {
+		copied := *ev
+		list = append(list, &copied)
+	}
+	return list
+}
@@ -0,0 +1,103 @@
+package cui
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAllowsProgLang(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ProgLangList = map[string]ProgLang{"py3": DefaultProgLangList()["py3"]}
+	session := NewSession(&Ticket{Id: "t1", Options: opts}, "", 3600)
+	if !session.AllowsProgLang("py3") {
+		t.Error("py3 not allowed")
+	}
+	for _, lang := range []string{"cpp", "", "cobol"} {
+		if session.AllowsProgLang(lang) {
+			t.Errorf("%q allowed", lang)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	practice := NewSession(&Ticket{Id: "t1"}, "", 3600)
+	if !practice.Open(time.Minute) || !practice.Started {
+		t.Error("fresh practice ticket not opened")
+	}
+	practice = NewSession(&Ticket{Id: "t2"}, "", 3600)
+	practice.Created = time.Now().Add(-time.Hour)
+	if practice.Open(time.Minute) {
+		t.Error("stale practice ticket opened")
+	}
+	issued := NewSession(&Ticket{Id: "t3"}, "ada@example.com", 3600)
+	issued.Created = time.Now().Add(-time.Hour)
+	if !issued.Open(time.Minute) {
+		t.Error("issued ticket did not wait for its candidate")
+	}
+}
+
+func TestBindAndSeen(t *testing.T) {
+	session := NewSession(&Ticket{Id: "t1"}, "", 3600)
+	if !session.Bind("10.0.0.1", "firefox") {
+		t.Fatal("Bind failed on a new session")
+	}
+	if session.Bind("10.0.0.2", "chrome") {
+		t.Error("bound twice")
+	}
+	if ev := session.Seen("10.0.0.1", "firefox"); ev != nil {
+		t.Errorf("Seen from the bound browser: %+v", ev)
+	}
+	if ev := session.Seen("10.0.0.3", "firefox"); ev == nil || ev.Kind != IP_CHANGED {
+		t.Errorf("Seen from a new address: %+v", ev)
+	}
+	if ev := session.Seen("10.0.0.3", "safari"); ev == nil || ev.Kind != AGENT_CHANGED {
+		t.Errorf("Seen with a new user agent: %+v", ev)
+	}
+	if !session.Shared() {
+		t.Error("not Shared after changing machines")
+	}
+}
+
+func TestReject(t *testing.T) {
+	session := NewSession(&Ticket{Id: "t1"}, "", 3600)
+	session.Bind("10.0.0.1", "firefox")
+	for i := 1; i <= 3; i++ {
+		ev, first := session.Reject("10.0.0.2", "chrome")
+		if first != (i == 1) || ev.Count != i || ev.Kind != REJECTED {
+			t.Errorf("Reject #%d: %+v, first %v", i, ev, first)
+		}
+	}
+	if n := len(session.EventLog()); n != 2 {
+		t.Errorf("%d events after rejecting one browser, want 2", n)
+	}
+	// Rejected requests do not count as the bound browser's.
+	if ev := session.Seen("10.0.0.1", "firefox"); ev != nil {
+		t.Errorf("Seen after a reject: %+v", ev)
+	}
+
+	for i := 0; i < 3*MAX_REJECTED; i++ {
+		session.Reject(fmt.Sprintf("10.1.0.%d", i), "chrome")
+	}
+	if n := len(session.EventLog()); n != 1+MAX_REJECTED {
+		t.Errorf("%d events after rejecting many browsers, want %d", n, 1+MAX_REJECTED)
+	}
+}
+
+func TestUnbind(t *testing.T) {
+	session := NewSession(&Ticket{Id: "t1"}, "", 3600)
+	if session.Unbind("rita") {
+		t.Error("unbound a session never bound")
+	}
+	session.Bind("10.0.0.1", "firefox")
+	if !session.Unbind("rita") {
+		t.Fatal("Unbind failed on a bound session")
+	}
+	if !session.Bind("10.0.0.2", "chrome") {
+		t.Error("another browser could not bind after Unbind")
+	}
+	events := session.EventLog()
+	if len(events) != 3 || events[1].Kind != UNBOUND || events[1].By != "rita" || events[2].Kind != BOUND {
+		t.Errorf("events: %+v", events)
+	}
+}
@@ -0,0 +1,44 @@
+package cui
+
+import (
+	"regexp"
+	"strings"
+)
+
+var tokenRe = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*|[0-9]+|[^\sA-Za-z0-9_]`)
+
+const shingleSize = 4
+
+func shingles(src string) map[string]bool {
+	tokens := tokenRe.FindAllString(src, -1)
+	set := map[string]bool{}
+	if len(tokens) == 0 {
+		return set
+	}
+	if len(tokens) < shingleSize {
+		set[strings.Join(tokens, " ")] = true
+		return set
+	}
+	for i := 0; i+shingleSize <= len(tokens); i++ {
+		set[strings.Join(tokens[i:i+shingleSize], " ")] = true
+	}
+	return set
+}
+
+// Similarity compares two solutions and returns a number between 0 (nothing
+// in common) and 1 (same code). Whitespace and layout are ignored; it is
+// the Jaccard index of the sets of four-token sequences.
+func Similarity(a, b string) float64 {
+	sa, sb := shingles(a), shingles(b)
+	common := 0
+	for s := range sa {
+		if sb[s] {
+			common++
+		}
+	}
+	union := len(sa) + len(sb) - common
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
@@ -0,0 +1,25 @@
+package cui
+
+import (
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	a := `int main() {
+  int n; cin >> n;
+  for (int i = 0; i < n; i++) cout << i << endl;
+}`
+	reformatted := "int main(){int n;cin>>n;for(int i=0;i<n;i++)cout<<i<<endl;}"
+	other := `def main():
+    print(sum(range(10)))`
+
+	if got := Similarity(a, reformatted); got != 1 {
+		t.Errorf("Similarity of reformatted code: got %v, want 1", got)
+	}
+	if got := Similarity(a, other); got > 0.1 {
+		t.Errorf("Similarity of unrelated code: got %v", got)
+	}
+	if got := Similarity("", ""); got != 0 {
+		t.Errorf("Similarity of empty solutions: got %v", got)
+	}
+}
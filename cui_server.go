@@ -1,7 +1,7 @@
 package main
 
 import (
-	"bytes"
+	"crypto/rand"
 	"flag"
 	"fmt"
 	docker_client "github.com/docker/engine-api/client"
@@ -9,15 +9,27 @@ import (
 	"github.com/labstack/echo/engine/standard"
 	mw "github.com/labstack/echo/middleware"
 	"github.com/labstack/gommon/log"
+	"github.com/maddyonline/g2/auth"
+	"github.com/maddyonline/g2/cache"
+	"github.com/maddyonline/g2/catalog"
+	"github.com/maddyonline/g2/compile"
 	"github.com/maddyonline/g2/cui"
 	"github.com/maddyonline/g2/frontend"
+	"github.com/maddyonline/g2/guard"
+	"github.com/maddyonline/g2/invite"
+	"github.com/maddyonline/g2/logging"
+	"github.com/maddyonline/g2/lsp"
+	"github.com/maddyonline/g2/problemset"
+	"github.com/maddyonline/g2/store"
+	"github.com/maddyonline/g2/webhook"
 	"github.com/maddyonline/problems"
 	"github.com/maddyonline/umpire"
 	"html/template"
 	"io"
-	"io/ioutil"
 	"net/http"
+	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
@@ -36,7 +48,9 @@ func (t *Template) Render(w io.Writer, name string, data interface{}, c echo.Con
 func loadTemplates(templatesDir string) *Template {
 	t := &Template{
 		// Cached templates
-		templates: template.Must(template.ParseFiles(filepath.Join(templatesDir, "cui.html"))),
+		templates: template.Must(template.ParseFiles(
+			filepath.Join(templatesDir, "cui.html"),
+			filepath.Join(templatesDir, "welcome.html"))),
 	}
 	return t
 }
@@ -45,6 +59,16 @@ var tasks = map[cui.TaskKey]*cui.Task{}
 var cuiSessions = map[string]*cui.Session{}
 var problemsList []*problems.Problem
 
+// stateLock guards tasks and cuiSessions.
+var stateLock = &sync.Mutex{}
+
+func getSession(ticketId string) (*cui.Session, bool) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+	session, ok := cuiSessions[ticketId]
+	return session, ok
+}
+
 func getSolutionRequest(c echo.Context) *cui.SolutionRequest {
 	return &cui.SolutionRequest{
 		Ticket:    c.FormValue("ticket"),
@@ -65,62 +89,120 @@ func getTaskRequest(c echo.Context) *cui.TaskRequest {
 	}
 }
 
+// humanLang is the language to talk to the candidate in: the one of their
+// ticket, or else their browser's.
+func humanLang(c echo.Context, session *cui.Session) string {
+	if session != nil {
+		if lang := session.HumanLang(); lang != "" {
+			return lang
+		}
+	}
+	return cui.MatchHumanLang(c.Request().Header().Get("Accept-Language"))
+}
+
 type ErrNotFound struct{}
 
 func (e ErrNotFound) Error() string {
 	return "Not Found"
 }
 
-func updateTask(solnReq *cui.SolutionRequest) (error, *cui.Task) {
+// updateTask saves the candidate's solution and returns a copy of the task
+// as saved, to judge.
+func updateTask(c echo.Context, solnReq *cui.SolutionRequest) (error, *cui.Task) {
+	session, ok := getSession(solnReq.Ticket)
+	if !ok {
+		return ErrNotFound{}, nil
+	}
+	if session.Refresh().Closed() {
+		return echo.NewHTTPError(http.StatusForbidden, cui.T(session.HumanLang(), cui.MSG_TICKET_CLOSED)), nil
+	}
+	if !session.AllowsProgLang(solnReq.ProgLang) {
+		return echo.NewHTTPError(http.StatusBadRequest, cui.T(session.HumanLang(), cui.MSG_PROG_LANG, solnReq.ProgLang)), nil
+	}
 	key := cui.TaskKey{solnReq.Ticket, solnReq.Task}
+	stateLock.Lock()
+	defer stateLock.Unlock()
 	task, ok := tasks[key]
 	if !ok {
 		return ErrNotFound{}, nil
 	}
-	log.Info(fmt.Sprintf("Updating task (%s): ProgLang from %s to %s", key, task.ProgLang, solnReq.ProgLang))
-	log.Info(fmt.Sprintf("Updating task (%s): CurrentSolution from %q to %q", key, task.CurrentSolution, solnReq.Solution))
+	log.Debugj(requestLine(c, "Updating task",
+		"prg_lang", solnReq.ProgLang,
+		"previous_prg_lang", task.ProgLang,
+		"solution", logging.Solution(solnReq.Solution)))
 	task.ProgLang = solnReq.ProgLang
 	task.CurrentSolution = solnReq.Solution
-	return nil, task
+	saved := *task
+	return nil, &saved
+}
+
+// wantsJSON reports whether the client asked for JSON.
+func wantsJSON(c echo.Context) bool {
+	c.Response().Header().Set(echo.HeaderVary, "Accept")
+	return strings.Contains(c.Request().Header().Get("Accept"), echo.MIMEApplicationJSON)
+}
+
+// respond replies with the XML document doc, or with its JSON form to
+// clients that ask for JSON.
+func respond(c echo.Context, doc, jsonDoc interface{}) error {
+	if wantsJSON(c) {
+		return c.JSON(http.StatusOK, jsonDoc)
+	}
+	return c.XML(http.StatusOK, doc)
 }
 
 func addCuiHandlers(e *echo.Echo) {
-	c := e.Group("/c")
+	c := e.Group("/c", boundToBrowser()...)
 	c.Post("/_start", func(c echo.Context) error {
-		session, ok := cuiSessions[c.FormValue("ticket")]
+		session, ok := getSession(c.FormValue("ticket"))
 		if !ok {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Attempt to start an invalid session")
+			return echo.NewHTTPError(http.StatusInternalServerError, cui.T(humanLang(c, nil), cui.MSG_INVALID_SESSION))
 		}
-		session.StartTime = time.Now()
+		session.Start()
+		saveSession(session)
+		notify(webhook.SESSION_STARTED, session)
 		return c.String(http.StatusOK, "Started")
 	})
 	c.Post("/_get_task", func(c echo.Context) error {
-		return c.XML(http.StatusOK, cli.GetTask(tasks, getTaskRequest(c)))
+		req := getTaskRequest(c)
+		session, ok := getSession(req.Ticket)
+		if ok && req.PreferServerProgLang && !session.AllowsProgLang(req.ProgLang) {
+			return echo.NewHTTPError(http.StatusBadRequest, cui.T(humanLang(c, session), cui.MSG_PROG_LANG, req.ProgLang))
+		}
+		stateLock.Lock()
+		defer stateLock.Unlock()
+		task := cli.GetTask(tasks, req)
+		if ok {
+			session.SetHumanLang(task.HumanLang)
+		}
+		return respond(c, task, task.ToJSON())
 	})
-	c.Get("/close/:ticket_id", func(c echo.Context) error {
-		log.Info("Params: ->%s<-, ->%s<-", c.P(0), c.P(1))
-		return c.Redirect(http.StatusTemporaryRedirect, "/")
+	c.Post("/close/:ticket_id", func(c echo.Context) error {
+		if session, ok := getSession(c.Param("ticket_id")); ok && session.Close(cui.FINISHED) {
+			saveSession(session)
+			closeLanguageServer(session.Ticket.Id)
+		}
+		return c.Redirect(http.StatusSeeOther, "/")
 	})
 
-	chk := e.Group("/chk")
+	chk := e.Group("/chk", boundToBrowser()...)
 	chk.Post("/clock", func(c echo.Context) error {
 		clkReq := &cui.ClockRequest{}
 		if err := c.Bind(clkReq); err != nil {
 			return err
 		}
-		log.Info(fmt.Sprintf("Clock Request: %v", clkReq))
-		oldlimit := time.Duration(clkReq.OldTimeLimit) * time.Second
+		stateLock.Lock()
 		resp := cli.GetClock(cuiSessions, clkReq)
-		newlimit := time.Duration(resp.NewTimeLimit) * time.Second
-		log.Info(fmt.Sprintf("Clock Request: OldLimit=%s", oldlimit))
-		log.Info(fmt.Sprintf("Clock Response: NewLimit=%s", newlimit))
-		return c.XML(http.StatusOK, resp)
+		stateLock.Unlock()
+		log.Debugj(requestLine(c, "Clock",
+			"old_time_limit_sec", clkReq.OldTimeLimit,
+			"new_time_limit_sec", resp.NewTimeLimit))
+		return respond(c, resp, resp.ToJSON())
 	})
 
 	chk.Post("/save", func(c echo.Context) error {
 		solnReq := getSolutionRequest(c)
-		log.Info(fmt.Sprintf("/verify solnReq: %#v", solnReq))
-		err, _ := updateTask(solnReq)
+		err, _ := updateTask(c, solnReq)
 		if err != nil {
 			return err
 		}
@@ -138,17 +220,26 @@ func addCuiHandlers(e *echo.Echo) {
 		handler := func(action Action) echo.HandlerFunc {
 			return func(c echo.Context) error {
 				solnReq := getSolutionRequest(c)
-				log.Info(fmt.Sprintf("%s\tsolnReq: %q", action.Path, solnReq))
-				err, task := updateTask(solnReq)
+				err, task := updateTask(c, solnReq)
 				if err != nil {
 					return err
 				}
-				resp := cli.GetVerifyStatus(task, solnReq, action.Mode)
-				log.Info(action.Path, "\t", "resp: ", resp)
-				return c.XML(http.StatusOK, resp)
+				session, _ := getSession(solnReq.Ticket)
+				judgeQueue.Add(1)
+				resp := cli.GetVerifyStatus(session, task, solnReq, action.Mode)
+				log.Infoj(requestLine(c, "Submitted",
+					"mode", action.Mode.String(),
+					"prg_lang", task.ProgLang,
+					"submission_id", resp.Id,
+					"result", resp.Result))
+				if action.Mode == cui.FINAL {
+					finishTask(session, task.Id)
+				}
+				saveSession(session)
+				return respond(c, resp, resp.ToJSON())
 			}
 		}(action)
-		chk.Post(action.Path, handler)
+		chk.Post(action.Path, handler, acceptSubmissions)
 	}
 
 	chk.Post("/status", func(c echo.Context) error {
@@ -158,43 +249,225 @@ func addCuiHandlers(e *echo.Echo) {
 		resp, ok := cui.Results.Store[fmt.Sprintf("%s/%s", ticket, verifyKey)]
 		cui.Results.Unlock()
 		if !ok {
-			resp = cui.LaterReply(verifyKey)
+			session, _ := getSession(ticket)
+			resp = cui.LaterReply(verifyKey, humanLang(c, session))
 		}
-		return c.XML(http.StatusOK, resp)
+		return respond(c, resp, resp.ToJSON())
 	})
 }
 
-func refreshProblemsList(problemsDir string, tmpl *template.Template, cli *cui.Client) {
-	probsList, err := problems.GetList(problemsDir, ioutil.Discard)
-	if err != nil {
-		log.Fatal(err)
-		return
+// finishTask closes the task after its final submission and finishes the
+// ticket once every task has been submitted.
+func finishTask(session *cui.Session, taskId string) {
+	stateLock.Lock()
+	if task, ok := tasks[cui.TaskKey{session.Ticket.Id, taskId}]; ok {
+		task.Status = "closed"
 	}
-	index, err := frontend.Index(tmpl, probsList)
-	if err != nil {
-		log.Fatal(err)
-		return
+	done := true
+	for _, id := range session.Ticket.Options.TaskNames {
+		if t, ok := tasks[cui.TaskKey{session.Ticket.Id, id}]; ok && t.Status != "closed" {
+			done = false
+		}
+	}
+	stateLock.Unlock()
+	if done && session.Close(cui.FINISHED) {
+		closeLanguageServer(session.Ticket.Id)
 	}
+}
+
+// buildCatalog indexes the problems for the index page. Problems with a
+// broken meta.json are listed without their tags and difficulty.
+func buildCatalog(problemsDir string, probsList map[string]*problems.Problem) *catalog.Index {
+	ix, errs := catalog.Load(problemsDir, probsList, cui.CUI_LANG_TO_MD)
+	for name, err := range errs {
+		log.Warnj(logging.Line("Problem metadata failed to load", "problem", name, "error", err.Error()))
+	}
+	return ix
+}
+
+var problemSet *problemset.Set
+
+// pruneSnapshots removes, every interval, the problem versions no open
+// ticket was created with.
+func pruneSnapshots(interval time.Duration) {
+	for {
+		// Tickets are created with the versions of cli, which may lag
+		// behind the problem set's.
+		inUse := map[string]bool{}
+		cli.Lock()
+		for name, version := range cli.Versions {
+			inUse[name+"-"+version] = true
+		}
+		cli.Unlock()
+		stateLock.Lock()
+		sessions := []*cui.Session{}
+		for _, session := range cuiSessions {
+			sessions = append(sessions, session)
+		}
+		stateLock.Unlock()
+		closed := map[string]bool{}
+		for _, session := range sessions {
+			if session.Refresh().Closed() {
+				closed[session.Ticket.Id] = true
+			}
+		}
+		stateLock.Lock()
+		for key, task := range tasks {
+			if !closed[key.TicketId] {
+				inUse[task.Id+"-"+task.ProblemVersion] = true
+			}
+		}
+		stateLock.Unlock()
+		removed, err := problemSet.Prune(func(name, version string) bool {
+			return inUse[name+"-"+version]
+		})
+		if err != nil {
+			log.Warnj(logging.Line("Pruning problem versions failed", "error", err.Error()))
+		}
+		if len(removed) > 0 {
+			log.Infoj(logging.Line("Pruned problem versions", "removed", len(removed)))
+		}
+		time.Sleep(interval)
+	}
+}
+
+// refreshProblemsList publishes the problems after some changed on disk.
+// Tickets already issued keep the statement and templates they copied.
+func refreshProblemsList(cli *cui.Client) {
+	probsList := problemSet.Problems()
+	ix := buildCatalog(problemSet.Dir, probsList)
 	cli.Mutex.Lock()
-	oldCount := len(cli.ProbsList)
 	cli.ProbsList = probsList
-	cli.Index = index
+	cli.Versions = problemSet.Versions()
+	cli.Translations = problemSet.Translations()
+	cli.Catalog = ix
 	cli.LastUpdated = time.Now()
-	log.Infof("Updated problems list: %d new problems", len(cli.ProbsList)-oldCount)
+	log.Infoj(logging.Line("Updated problems list", "problems", len(probsList)))
 	cli.Mutex.Unlock()
+	problemReloads.Inc()
 }
 
 const PORT = "3000"
 
+// PROBLEMS_DIR is where problems are read from, relative to the working
+// directory.
+const PROBLEMS_DIR = "../../maddyonline/problems"
+
+type secret struct {
+	Key []byte `json:"key"`
+}
+
+// loadSecret returns the key used to sign cookies: the given one if any,
+// otherwise one generated on first start and kept in the store.
+func loadSecret(db *store.Store, given string) ([]byte, error) {
+	if given != "" {
+		return []byte(given), nil
+	}
+	s := &secret{}
+	err := db.Get("secrets", "cookie", s)
+	if _, ok := err.(store.ErrNotFound); ok {
+		s.Key = make([]byte, 32)
+		if _, err := rand.Read(s.Key); err != nil {
+			return nil, err
+		}
+		return s.Key, db.Put("secrets", "cookie", s)
+	}
+	return s.Key, err
+}
+
 var cli *cui.Client
 
+var resultCache *cache.Cache
+
+// commands are run as "g2 <command> [flags]" instead of starting the server.
+var commands = map[string]func(args []string) error{
+	"cli":     cliCommand,
+	"export":  exportCommand,
+	"problem": problemCommand,
+}
+
 func main() {
-	var port string
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	var port, dataDir, adminPassword, cookieSecret, lspConfig, compilersConfig string
+	var cacheSize int
+	var shutdownTimeout time.Duration
+	var logLevel string
 	flag.StringVar(&port, "port", PORT, "port")
+	flag.StringVar(&dataDir, "data", "data", "directory where tickets are stored")
+	flag.StringVar(&adminPassword, "admin-password", os.Getenv("G2_ADMIN_PASSWORD"), "password of the admin account created on first start")
+	flag.StringVar(&cookieSecret, "secret", os.Getenv("G2_SECRET"), "key for signing cookies (generated and stored if empty)")
+	flag.IntVar(&cacheSize, "cache-size", 64, "megabytes of compile and run results to keep (0 turns the cache off)")
+	flag.StringVar(&compilersConfig, "compilers", "", "JSON file of compile-only commands by language (sandboxed defaults if empty)")
+	flag.StringVar(&lspConfig, "lsp-servers", "", "JSON file of language server commands by language (sandboxed defaults if empty)")
+	flag.IntVar(&lspMaxServers, "lsp-max-servers", 32, "language servers running at once")
+	flag.StringVar(&logLevel, "log-level", "info", "least severe log lines written: debug, info, warn, error or off")
+	flag.DurationVar(&shutdownTimeout, "shutdown-timeout", 9*time.Minute, "how long to wait for solutions being judged on SIGTERM")
+	flag.BoolVar(&logging.LogSolutions, "log-solutions", false, "log candidates' solutions instead of their size and hash")
 	flag.Parse()
-	log.Info(fmt.Sprintf("Using Port=%s", port))
+	lvl, err := logging.ParseLevel(logLevel)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	log.SetLevel(lvl)
+	log.Infoj(logging.Line("Starting", "port", port))
 
-	problemsDir, err := filepath.Abs("../../maddyonline/problems")
+	db, err = store.Open(dataDir)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	if err := loadSessions(); err != nil {
+		log.Fatal(err)
+		return
+	}
+	authn := auth.New(db)
+	if err := bootstrapAdmin(authn, adminPassword); err != nil {
+		log.Fatal(err)
+		return
+	}
+	invites = invite.New(db)
+	hooks = webhook.New(db)
+	if resumed, err := hooks.Resume(); err != nil {
+		log.Errorj(logging.Line("Resuming webhook deliveries failed", "error", err.Error()))
+	} else if resumed > 0 {
+		log.Infoj(logging.Line("Resumed webhook deliveries", "deliveries", resumed))
+	}
+	go watchTimeouts(30 * time.Second)
+	key, err := loadSecret(db, cookieSecret)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	signer = guard.NewSigner(key)
+	signer.Message = guardMessage
+	compilers := compile.DefaultCompilers()
+	if compilersConfig != "" {
+		if compilers, err = compile.LoadCompilers(compilersConfig); err != nil {
+			log.Fatal(err)
+			return
+		}
+	}
+	if cacheSize > 0 {
+		resultCache = cache.New(int64(cacheSize) << 20)
+	}
+	lspServers = lsp.DefaultServers()
+	if lspConfig != "" {
+		if lspServers, err = lsp.LoadServers(lspConfig); err != nil {
+			log.Fatal(err)
+			return
+		}
+	}
+
+	problemsDir, err := filepath.Abs(PROBLEMS_DIR)
 	if err != nil {
 		log.Fatal(err)
 		return
@@ -206,43 +479,62 @@ func main() {
 	}
 	umpireAgent := &umpire.Agent{dcli, problemsDir}
 
-	probsList, err := problems.GetList(problemsDir, ioutil.Discard)
+	// The judge mounts snapshots into containers, which takes absolute paths.
+	snapshotsDir, err := filepath.Abs(filepath.Join(dataDir, "problem_versions"))
 	if err != nil {
 		log.Fatal(err)
 		return
 	}
+	problemSet = problemset.New(problemsDir, snapshotsDir, problemset.GetList)
+	if err := problemSet.LoadAll(); err != nil {
+		log.Fatal(err)
+		return
+	}
+	probsList := problemSet.Problems()
 
 	tmpl := template.Must(template.ParseFiles(
 		"frontend/templates/problems_list.tpl",
 		"frontend/templates/main.tpl"))
-	index, err := frontend.Index(tmpl, probsList)
-	if err != nil {
-		log.Fatal(err)
-		return
-	}
+	reviewTmpl := template.Must(template.ParseFiles(
+		"frontend/templates/review_list.tpl",
+		"frontend/templates/review_ticket.tpl",
+		"frontend/templates/report.tpl"))
+	editorTmpl := template.Must(template.ParseFiles(
+		"frontend/templates/editor_list.tpl",
+		"frontend/templates/editor.tpl"))
 
 	cli = &cui.Client{
-		Agent:       umpireAgent,
-		ProbsList:   probsList,
-		Index:       index,
+		Agent:        umpireAgent,
+		ProbsList:    probsList,
+		Versions:     problemSet.Versions(),
+		Translations: problemSet.Translations(),
+		AgentFor: func(task *cui.Task) *umpire.Agent {
+			return &umpire.Agent{dcli, problemSet.VersionDir(task.Id, task.ProblemVersion)}
+		},
+		Compilers:   compilers,
+		Cache:       resultCache,
+		Catalog:     buildCatalog(problemsDir, probsList),
 		LastUpdated: time.Now(),
-		Mutex:       &sync.Mutex{},
+		OnVerdict: func(session *cui.Session, sub *cui.Submission) {
+			observeVerdict(sub)
+			saveSession(session)
+			if sub.Mode == cui.FINAL.String() {
+				notifyFinal(session, sub)
+			}
+		},
+		OnExecutorFailure: func(task *cui.Task, err error) {
+			executorFailures.Inc(progLangLabel(task.ProgLang))
+		},
+		Mutex: &sync.Mutex{},
 	}
 
-	ticker := time.NewTicker(1 * time.Minute)
+	problemSet.OnChange = func() { refreshProblemsList(cli) }
+	go pruneSnapshots(time.Hour)
 	quit := make(chan struct{})
-	defer func() { quit <- struct{}{} }()
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				refreshProblemsList(problemsDir, tmpl, cli)
-			case <-quit:
-				ticker.Stop()
-				return
-			}
-		}
-	}()
+	if err := problemSet.Watch(quit); err != nil {
+		log.Fatal(err)
+		return
+	}
 
 	rootDir, err := filepath.Abs(".")
 	if err != nil {
@@ -257,15 +549,24 @@ func main() {
 	e.Pre(mw.RemoveTrailingSlash())
 
 	// Middleware
-	e.Use(mw.Logger())
+	e.Use(requestID, accessLog)
 	//e.Use(mw.Recover())
 	e.Get("/ping", func(c echo.Context) error {
 		return c.String(http.StatusOK, "pong")
 	})
+	addMetricsHandlers(e, authn)
+	addHealthHandlers(e)
 
 	// Frontend
 	e.Get("/", func(c echo.Context) error {
-		return c.ServeContent(bytes.NewReader(cli.Index), "index.html", cli.LastUpdated)
+		cli.Lock()
+		ix := cli.Catalog
+		cli.Unlock()
+		b, err := frontend.Index(tmpl, ix.Search(catalog.ParseQuery(c.QueryParams())))
+		if err != nil {
+			return err
+		}
+		return c.HTML(http.StatusOK, string(b))
 	})
 	//filepath.Join(rootDir, "frontend/index.html"))
 	e.Static("/static/", filepath.Join(rootDir, "frontend/static"))
@@ -278,34 +579,80 @@ func main() {
 	// CUI entry point
 	e.Get("/cui/new", func(c echo.Context) error {
 		problem_id := c.QueryParam("problem_id")
-		log.Info(fmt.Sprintf("Got problem_id: %s", problem_id))
+		log.Infoj(requestLine(c, "Practice ticket requested", "problem_id", problem_id))
 		if problem_id == "" {
 			return ErrNotFound{}
 		}
-		ticket := cli.NewTicket(tasks, problem_id)
-		cuiSessions[ticket.Id] = &cui.Session{TimeLimit: 3600, Created: time.Now(), Ticket: ticket}
+		stateLock.Lock()
+		ticket, err := cli.NewTicket(tasks, []string{problem_id}, nil)
+		if err != nil {
+			stateLock.Unlock()
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		session := cui.NewSession(ticket, "", 3600)
+		cuiSessions[ticket.Id] = session
+		stateLock.Unlock()
+		saveSession(session)
+		ticketsCreated.Inc()
+		notify(webhook.TICKET_CREATED, session)
 		return c.JSON(http.StatusOK, map[string]string{"ticket_id": ticket.Id, "problem_id": problem_id})
 	})
 	e.Get("/cui/:ticket_id", func(c echo.Context) error {
 		ticket_id := c.Param("ticket_id")
-		log.Info("Ticket: %s", ticket_id)
-		session, ok := cuiSessions[ticket_id]
+		session, ok := getSession(ticket_id)
 		if !ok {
-			return echo.NewHTTPError(http.StatusNotFound, "No valid session found")
+			return echo.NewHTTPError(http.StatusNotFound, cui.T(humanLang(c, nil), cui.MSG_NO_SESSION))
 		}
-		if !session.Started {
-			if time.Now().Sub(session.Created) > time.Duration(10*time.Second) {
-				return echo.NewHTTPError(http.StatusNotFound, "Session Expired")
-			}
-			session.Started = true
+		lang := humanLang(c, session)
+		if session.Refresh().Closed() {
+			return echo.NewHTTPError(http.StatusForbidden, cui.T(lang, cui.MSG_TICKET_CLOSED))
+		}
+		// Tickets issued through the admin API wait for their candidate.
+		if !session.Open(10 * time.Second) {
+			return echo.NewHTTPError(http.StatusNotFound, cui.T(lang, cui.MSG_SESSION_EXPIRED))
 		}
-		log.Info("Session Started? %v", session.Started)
-		return c.Render(http.StatusOK, "cui.html", map[string]interface{}{"Title": "Goonj2", "Ticket": session.Ticket})
+		if bound, ok := signer.Cookie(c, sessionCookie); ok && bound == ticket_id {
+			seenSession(c, session)
+		} else if !bindSession(c, session) {
+			rejectSession(c, ticket_id)
+			return echo.NewHTTPError(http.StatusForbidden, cui.T(lang, cui.MSG_OTHER_BROWSER))
+		}
+		log.Infoj(requestLine(c, "Opened ticket"))
+		if wantsJSON(c) {
+			return c.JSON(http.StatusOK, &cui.TicketJSON{ticket_id, signer.CSRFToken(ticket_id), session.Ticket.Options})
+		}
+		return c.Render(http.StatusOK, "cui.html", map[string]interface{}{
+			"Title":     "Goonj2",
+			"Ticket":    session.Ticket,
+			"CSRFToken": signer.CSRFToken(ticket_id),
+		})
 	})
 
 	// Remaining CUI handlers
 	addCuiHandlers(e)
 
+	// Language servers for the editor
+	addLSPHandlers(e)
+
+	// Candidate invitations
+	addInviteHandlers(e)
+
+	// Admin API
+	addAdminHandlers(e, authn, reviewTmpl)
+
+	// Reviewer dashboard
+	addReviewHandlers(e, authn, reviewTmpl)
+
+	// Problem editor
+	addEditorHandlers(e, authn, editorTmpl)
+
 	// Start server
-	e.Run(standard.New(fmt.Sprintf(":%s", port)))
+	server := standard.New(fmt.Sprintf(":%s", port))
+	stopped := stopOnSignal(server, shutdownTimeout, quit)
+	if err := e.Run(server); err != http.ErrServerClosed {
+		log.Fatal(err)
+		return
+	}
+	<-stopped
+	log.Infoj(logging.Line("Stopped"))
 }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"github.com/labstack/echo"
+	"github.com/labstack/gommon/log"
+	"github.com/maddyonline/g2/auth"
+	"github.com/maddyonline/g2/authoring"
+	"github.com/maddyonline/g2/cui"
+	"github.com/maddyonline/g2/frontend"
+	"github.com/maddyonline/g2/problemset"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+)
+
+// editorLock serializes saves, which replace whole problem directories.
+var editorLock = &sync.Mutex{}
+
+// editorCSRFKey makes the editor's CSRF token specific to the admin.
+func editorCSRFKey(c echo.Context) string {
+	return "editor:" + auth.CurrentUser(c).Name
+}
+
+// bindDraft reads the draft the editor posts for the problem in the URL.
+func bindDraft(c echo.Context) (*authoring.Draft, error) {
+	d := &authoring.Draft{}
+	if err := c.Bind(d); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	d.Name = c.Param("name")
+	return d, nil
+}
+
+// addEditorHandlers serves the problem editor to admins. Saved problems are
+// written to the problems directory and reloaded like any other change.
+func addEditorHandlers(e *echo.Echo, a *auth.Auth, tmpl *template.Template) {
+	ed := e.Group("/editor", a.Middleware(), auth.Require(), signer.CSRF(editorCSRFKey))
+
+	ed.Get("", func(c echo.Context) error {
+		if name := c.QueryParam("new"); name != "" {
+			if err := authoring.ValidName(name); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			return c.Redirect(http.StatusFound, (&url.URL{Path: "/editor/" + name}).String())
+		}
+		entries := []*frontend.EditorEntry{}
+		for _, st := range problemStatuses() {
+			entries = append(entries, &frontend.EditorEntry{st.Name, st.Title, st.Version, st.Error})
+		}
+		b, err := frontend.EditorList(tmpl, entries)
+		if err != nil {
+			return err
+		}
+		return c.HTML(http.StatusOK, string(b))
+	})
+
+	ed.Get("/:name", func(c echo.Context) error {
+		name := c.Param("name")
+		if err := authoring.ValidName(name); err != nil {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		d, err := authoring.LoadDraft(problemSet.Dir, name)
+		isNew := os.IsNotExist(err)
+		if isNew {
+			d, err = authoring.NewDraft(name, nil)
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		page := frontend.NewEditorPage(d, isNew, signer.CSRFToken(editorCSRFKey(c)))
+		b, err := frontend.Editor(tmpl, page)
+		if err != nil {
+			return err
+		}
+		return c.HTML(http.StatusOK, string(b))
+	})
+
+	ed.Post("/:name/preview", func(c echo.Context) error {
+		d, err := bindDraft(c)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, map[string]string{"html": cui.RenderDescription(d.Statement())})
+	})
+
+	ed.Post("/:name", func(c echo.Context) error {
+		d, err := bindDraft(c)
+		if err != nil {
+			return err
+		}
+		editorLock.Lock()
+		defer editorLock.Unlock()
+		if err := authoring.Save(problemSet.Dir, d); err != nil {
+			if invalid, ok := err.(authoring.ErrInvalidDraft); ok {
+				return c.JSON(http.StatusBadRequest, map[string][]string{"errors": invalid.Problems})
+			}
+			return err
+		}
+		log.Infoj(requestLine(c, "Saved problem", "user", auth.CurrentUser(c).Name, "problem", d.Name))
+
+		// Reload now rather than wait for the watcher, to report the outcome.
+		if problemSet.Reload(d.Name) {
+			refreshProblemsList(cli)
+		}
+		warnings := []string{}
+		for _, err := range authoring.Validate(problemSet.Dir, d.Name, problemset.GetList) {
+			warnings = append(warnings, err.Error())
+		}
+		version := problemSet.Versions()[d.Name]
+		if _, failed := problemSet.Errors()[d.Name]; failed {
+			// Still serving the previous version.
+			version = ""
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"version":  version,
+			"warnings": warnings,
+		})
+	})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/labstack/echo"
+	"github.com/maddyonline/g2/cui"
+	"github.com/maddyonline/g2/export"
+	"github.com/maddyonline/g2/store"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+)
+
+// byTicketCreated orders exported rows oldest first.
+type byTicketCreated []*export.Row
+
+func (a byTicketCreated) Len() int           { return len(a) }
+func (a byTicketCreated) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byTicketCreated) Less(i, j int) bool { return a[i].Created.Before(a[j].Created) }
+
+func exportRows(records []*ticketRecord, from, to time.Time) []*export.Row {
+	rows := []*export.Row{}
+	for _, rec := range records {
+		row := export.NewRow(rec.Session, rec.Tasks)
+		if row.In(from, to) {
+			rows = append(rows, row)
+		}
+	}
+	sort.Sort(byTicketCreated(rows))
+	return rows
+}
+
+// liveRecords returns the tickets held by the server.
+func liveRecords() []*ticketRecord {
+	stateLock.Lock()
+	sessions := []*cui.Session{}
+	for _, session := range cuiSessions {
+		sessions = append(sessions, session)
+	}
+	stateLock.Unlock()
+	records := []*ticketRecord{}
+	for _, session := range sessions {
+		records = append(records, &ticketRecord{session, sessionTasks(session)})
+	}
+	return records
+}
+
+// addExportHandlers serves GET /export?from=2016-05-01&to=2016-05-31&format=csv.
+func addExportHandlers(api *echo.Group, mw ...echo.MiddlewareFunc) {
+	api.Get("/export", func(c echo.Context) error {
+		format := c.QueryParam("format")
+		if format == "" {
+			format = export.CSV
+		}
+		if format != export.CSV && format != export.JSONL {
+			return echo.NewHTTPError(http.StatusBadRequest, export.ErrUnknownFormat{format}.Error())
+		}
+		from, to, err := export.ParseRange(c.QueryParam("from"), c.QueryParam("to"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		rows := exportRows(liveRecords(), from, to)
+		c.Response().Header().Set(echo.HeaderContentType, export.ContentType(format))
+		c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="tickets.%s"`, format))
+		c.Response().WriteHeader(http.StatusOK)
+		return export.Write(c.Response(), format, rows)
+	}, mw...)
+}
+
+// exportCommand is "g2 export": it reads tickets straight from the data
+// directory, so the server need not be running.
+func exportCommand(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dataDir := fs.String("data", "data", "directory where tickets are stored")
+	from := fs.String("from", "", "first day to export (2006-01-02 or RFC 3339)")
+	to := fs.String("to", "", "last day to export (2006-01-02 or RFC 3339)")
+	format := fs.String("format", export.CSV, "csv or jsonl")
+	out := fs.String("o", "", "output file (default stdout)")
+	fs.Parse(args)
+
+	if *format != export.CSV && *format != export.JSONL {
+		return export.ErrUnknownFormat{*format}
+	}
+	start, end, err := export.ParseRange(*from, *to)
+	if err != nil {
+		return err
+	}
+	s, err := store.Open(*dataDir)
+	if err != nil {
+		return err
+	}
+	ids, err := s.List("tickets")
+	if err != nil {
+		return err
+	}
+	records := []*ticketRecord{}
+	for _, id := range ids {
+		rec := &ticketRecord{}
+		if err := s.Get("tickets", id, rec); err != nil {
+			return err
+		}
+		records = append(records, rec)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return export.Write(w, *format, exportRows(records, start, end))
+}
@@ -0,0 +1,188 @@
+// Package export dumps assessment results as CSV or JSON lines.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/maddyonline/g2/cui"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CSV   = "csv"
+	JSONL = "jsonl"
+)
+
+type ErrUnknownFormat struct {
+	Name string
+}
+
+func (e ErrUnknownFormat) Error() string {
+	return fmt.Sprintf("Unknown export format %q (want %s or %s)", e.Name, CSV, JSONL)
+}
+
+// Row is one exported ticket. Problems, ProgLangs and Verdicts are in the
+// same order.
+type Row struct {
+	Ticket    string           `json:"ticket"`
+	Candidate string           `json:"candidate"`
+	Status    cui.TicketStatus `json:"status"`
+	Problems  []string         `json:"problems"`
+	ProgLangs []string         `json:"prg_langs"`
+	Verdicts  []string         `json:"verdicts"`
+	Score     int              `json:"score"`
+	Created   time.Time        `json:"created"`
+	StartTime *time.Time       `json:"start_time"`
+	EndTime   *time.Time       `json:"end_time"`
+	TimeUsed  int              `json:"time_used_sec"`
+}
+
+var header = []string{
+	"ticket", "candidate", "status", "problems", "prg_langs", "verdicts",
+	"score", "created", "start_time", "end_time", "time_used_sec",
+}
+
+// verdict is "passed", "failed" or "pending" for judged tasks, "none"
+// otherwise.
+func verdict(res *cui.TaskResult) string {
+	if res.Status == cui.NOT_JUDGED {
+		return "none"
+	}
+	return string(res.Status)
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func NewRow(session *cui.Session, tasks []*cui.Task) *Row {
+	session.Refresh()
+	results := session.Results(tasks)
+	r := &Row{
+		Score:    cui.Score(results),
+		TimeUsed: int(session.TimeUsed() / time.Second),
+	}
+	for _, res := range results {
+		r.Problems = append(r.Problems, res.TaskId)
+		r.ProgLangs = append(r.ProgLangs, res.ProgLang)
+		r.Verdicts = append(r.Verdicts, verdict(res))
+	}
+	session.Lock()
+	defer session.Unlock()
+	r.Ticket = session.Ticket.Id
+	r.Candidate = session.Candidate
+	r.Status = session.Status
+	r.Created = session.Created
+	r.StartTime = optionalTime(session.StartTime)
+	r.EndTime = optionalTime(session.EndTime)
+	return r
+}
+
+// In reports whether the ticket was created in [from, to). Zero times leave
+// that end of the range open.
+func (r *Row) In(from, to time.Time) bool {
+	return (from.IsZero() || !r.Created.Before(from)) && (to.IsZero() || r.Created.Before(to))
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func (r *Row) record() []string {
+	return []string{
+		r.Ticket,
+		r.Candidate,
+		string(r.Status),
+		strings.Join(r.Problems, ";"),
+		strings.Join(r.ProgLangs, ";"),
+		strings.Join(r.Verdicts, ";"),
+		strconv.Itoa(r.Score),
+		r.Created.Format(time.RFC3339),
+		formatTime(r.StartTime),
+		formatTime(r.EndTime),
+		strconv.Itoa(r.TimeUsed),
+	}
+}
+
+// WriteCSV writes rows with a header line. Lists are joined with ";".
+func WriteCSV(w io.Writer, rows []*Row) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if err := cw.Write(r.record()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes one JSON object per line.
+func WriteJSON(w io.Writer, rows []*Row) error {
+	enc := json.NewEncoder(w)
+	for _, r := range rows {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Write(w io.Writer, format string, rows []*Row) error {
+	switch format {
+	case CSV:
+		return WriteCSV(w, rows)
+	case JSONL:
+		return WriteJSON(w, rows)
+	}
+	return ErrUnknownFormat{format}
+}
+
+// ContentType is the media type of the given format.
+func ContentType(format string) string {
+	if format == JSONL {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// ParseDay parses a date ("2006-01-02") or a RFC 3339 time. An empty string
+// is the zero time.
+func ParseDay(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// ParseRange parses the from and to ends of a range; a date given as to
+// includes that whole day.
+func ParseRange(from, to string) (time.Time, time.Time, error) {
+	start, err := ParseDay(from)
+	if err != nil {
+		return start, start, err
+	}
+	end, err := ParseDay(to)
+	if err != nil {
+		return start, end, err
+	}
+	if len(to) == len("2006-01-02") {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end, nil
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/maddyonline/g2/cui"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleRow() *Row {
+	ticket := &cui.Ticket{Id: "t1", Options: &cui.Options{TaskNames: []string{"p1", "p2"}}}
+	session := cui.NewSession(ticket, "ann@example.com", 3600)
+	session.Created = time.Date(2016, 5, 3, 10, 0, 0, 0, time.UTC)
+	session.Start()
+	p1 := &cui.Task{Id: "p1", ProgLang: "py3"}
+	sub := session.AddSubmission("s1", p1, cui.FINAL)
+	v := &cui.VerifyStatus{Result: "OK"}
+	v.Extra.Compile.OK = 1
+	v.Extra.Example.OK = 1
+	for _, s := range []*cui.Status{&v.Extra.TestData0, &v.Extra.TestData1, &v.Extra.TestData2, &v.Extra.TestData3, &v.Extra.TestData4} {
+		s.OK = 1
+	}
+	session.SetVerdict(sub, v)
+	return NewRow(session, []*cui.Task{p1, {Id: "p2", ProgLang: "cpp"}})
+}
+
+func TestNewRow(t *testing.T) {
+	r := sampleRow()
+	if r.Ticket != "t1" || r.Candidate != "ann@example.com" || r.Status != cui.STARTED {
+		t.Errorf("got %+v", r)
+	}
+	if strings.Join(r.Verdicts, ",") != "passed,none" || strings.Join(r.ProgLangs, ",") != "py3,cpp" {
+		t.Errorf("verdicts %v, languages %v", r.Verdicts, r.ProgLangs)
+	}
+	if r.Score != 50 || r.StartTime == nil || r.EndTime != nil {
+		t.Errorf("got %+v", r)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	rows := []*Row{sampleRow()}
+	var b bytes.Buffer
+	if err := Write(&b, CSV, rows); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "ticket,candidate,") {
+		t.Fatalf("csv: %q", b.String())
+	}
+	if !strings.HasPrefix(lines[1], "t1,ann@example.com,started,p1;p2,py3;cpp,passed;none,50,2016-05-03T10:00:00Z,") {
+		t.Errorf("csv row: %q", lines[1])
+	}
+
+	b.Reset()
+	if err := Write(&b, JSONL, rows); err != nil {
+		t.Fatal(err)
+	}
+	got := &Row{}
+	if err := json.Unmarshal(b.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Ticket != "t1" || got.Score != 50 || len(got.Problems) != 2 {
+		t.Errorf("json: %s", b.String())
+	}
+
+	if err := Write(&b, "xml", rows); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestRange(t *testing.T) {
+	from, to, err := ParseRange("2016-05-01", "2016-05-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Row{}
+	for _, c := range []struct {
+		created time.Time
+		in      bool
+	}{
+		{time.Date(2016, 4, 30, 23, 0, 0, 0, time.Local), false},
+		{time.Date(2016, 5, 1, 0, 0, 0, 0, time.Local), true},
+		{time.Date(2016, 5, 3, 23, 59, 0, 0, time.Local), true},
+		{time.Date(2016, 5, 4, 0, 0, 0, 0, time.Local), false},
+	} {
+		r.Created = c.created
+		if r.In(from, to) != c.in {
+			t.Errorf("In(%v) = %v", c.created, !c.in)
+		}
+	}
+	if !r.In(time.Time{}, time.Time{}) {
+		t.Error("an open range should contain everything")
+	}
+	if _, _, err := ParseRange("yesterday", ""); err == nil {
+		t.Error("expected a parse error")
+	}
+}
@@ -0,0 +1,78 @@
+package frontend
+
+import (
+	"bytes"
+	"github.com/maddyonline/g2/authoring"
+	"github.com/maddyonline/g2/catalog"
+	"github.com/maddyonline/g2/cui"
+	"html/template"
+	"sort"
+)
+
+// EditorEntry is a problem in the list of the problem editor.
+type EditorEntry struct {
+	Name    string
+	Title   string
+	Version string
+	Error   string
+}
+
+// EditorPage is the editor of one problem.
+type EditorPage struct {
+	Draft        *authoring.Draft
+	New          bool
+	Languages    []string
+	Difficulties []catalog.Difficulty
+	CSRFToken    string
+	Preview      template.HTML
+	// Tests are the examples, then the hidden tests.
+	Tests []*EditorTest
+}
+
+type EditorTest struct {
+	Example bool
+	*authoring.Test
+}
+
+// BlankTest is the form the editor adds tests from.
+func (p *EditorPage) BlankTest() *EditorTest {
+	return &EditorTest{false, &authoring.Test{}}
+}
+
+func NewEditorPage(d *authoring.Draft, isNew bool, csrfToken string) *EditorPage {
+	page := &EditorPage{
+		Draft:        d,
+		New:          isNew,
+		Difficulties: catalog.Difficulties,
+		CSRFToken:    csrfToken,
+		// Sanitized by RenderDescription.
+		Preview: template.HTML(cui.RenderDescription(d.Statement())),
+	}
+	for lang := range authoring.FileNames {
+		page.Languages = append(page.Languages, lang)
+	}
+	sort.Strings(page.Languages)
+	for _, t := range d.Examples {
+		page.Tests = append(page.Tests, &EditorTest{true, t})
+	}
+	for _, t := range d.Tests {
+		page.Tests = append(page.Tests, &EditorTest{false, t})
+	}
+	return page
+}
+
+func EditorList(tmpl *template.Template, entries []*EditorEntry) ([]byte, error) {
+	var b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&b, "editor_list", entries); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func Editor(tmpl *template.Template, page *EditorPage) ([]byte, error) {
+	var b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&b, "editor", page); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
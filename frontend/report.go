@@ -0,0 +1,89 @@
+package frontend
+
+import (
+	"bytes"
+	"github.com/maddyonline/g2/cui"
+	"html/template"
+	"time"
+)
+
+// SimilarityThreshold is the similarity above which two solutions of the
+// same task get flagged in reports.
+const SimilarityThreshold = 0.8
+
+// Match is another candidate's solution that looks like this one.
+type Match struct {
+	TicketId   string
+	Candidate  string
+	Similarity int
+}
+
+// ReportTask is one task of a report along with its statement.
+type ReportTask struct {
+	*cui.TaskResult
+	Description template.HTML
+	Matches     []*Match
+}
+
+// Report is the summary of an assessment sent to hiring managers.
+type Report struct {
+	*Review
+	Tasks     []*ReportTask
+	Generated time.Time
+}
+
+// NewReport builds the report of review. tasks provide the descriptions
+// and others are the reviews solutions get compared against.
+func NewReport(review *Review, tasks []*cui.Task, others []*Review) *Report {
+	byId := map[string]*cui.Task{}
+	for _, task := range tasks {
+		byId[task.Id] = task
+	}
+	r := &Report{Review: review, Generated: time.Now()}
+	for _, res := range review.Results {
+		rt := &ReportTask{TaskResult: res}
+		task, ok := byId[res.TaskId]
+		if ok {
+			rt.Description = template.HTML(task.Description)
+		}
+		// Untouched templates look alike; nothing to compare.
+		untouched := ok && res.Solution == task.Templates[cui.CUI_LANG_TO_MD[res.ProgLang]]
+		if untouched || res.Submitted.IsZero() {
+			r.Tasks = append(r.Tasks, rt)
+			continue
+		}
+		for _, other := range others {
+			if other.Id == review.Id {
+				continue
+			}
+			for _, o := range other.Results {
+				if o.TaskId != res.TaskId || o.Submitted.IsZero() {
+					continue
+				}
+				if sim := cui.Similarity(res.Solution, o.Solution); sim >= SimilarityThreshold {
+					rt.Matches = append(rt.Matches, &Match{other.Id, other.Candidate, int(sim * 100)})
+				}
+			}
+		}
+		r.Tasks = append(r.Tasks, rt)
+	}
+	return r
+}
+
+// Flagged reports whether any solution looks copied.
+func (r *Report) Flagged() bool {
+	for _, t := range r.Tasks {
+		if len(t.Matches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func RenderReport(tmpl *template.Template, report *Report) ([]byte, error) {
+	var b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&b, "report", report); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
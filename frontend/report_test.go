@@ -0,0 +1,73 @@
+package frontend
+
+import (
+	"github.com/maddyonline/g2/cui"
+	"testing"
+	"time"
+)
+
+const TEMPL_PY = "def solution(a):\n    # write your code here\n    pass\n"
+
+func reviewOf(id string, solutions map[string]string) *Review {
+	r := &Review{Id: id, Candidate: id + "@example.com"}
+	for taskId, solution := range solutions {
+		r.Results = append(r.Results, &cui.TaskResult{
+			TaskId:    taskId,
+			ProgLang:  "py3",
+			Solution:  solution,
+			Submitted: time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC),
+		})
+	}
+	return r
+}
+
+func TestNewReport(t *testing.T) {
+	tasks := []*cui.Task{
+		{Id: "sum", Description: "<p>Add them up</p>", Templates: map[string]string{"python": TEMPL_PY}},
+		{Id: "max", Templates: map[string]string{"python": TEMPL_PY}},
+	}
+	copied := "def solution(a):\n    total = 0\n    for x in a:\n        total += x\n    return total\n"
+	review := reviewOf("t1", map[string]string{"sum": copied, "max": TEMPL_PY})
+	others := []*Review{
+		review,
+		reviewOf("t2", map[string]string{"sum": copied, "max": TEMPL_PY}),
+		reviewOf("t3", map[string]string{"sum": "def solution(a):\n    return sum(a)\n"}),
+	}
+
+	report := NewReport(review, tasks, others)
+	if len(report.Tasks) != 2 {
+		t.Fatalf("%d tasks, want 2", len(report.Tasks))
+	}
+	byId := map[string]*ReportTask{}
+	for _, rt := range report.Tasks {
+		byId[rt.TaskId] = rt
+	}
+	sum := byId["sum"]
+	if sum.Description != "<p>Add them up</p>" {
+		t.Errorf("description: %q", sum.Description)
+	}
+	if len(sum.Matches) != 1 || sum.Matches[0].TicketId != "t2" || sum.Matches[0].Similarity != 100 {
+		t.Errorf("sum matches: %+v", sum.Matches)
+	}
+	// Both candidates left the py3 template untouched; that is no copying.
+	if m := byId["max"].Matches; len(m) != 0 {
+		t.Errorf("untouched template flagged: %+v", m)
+	}
+	if !report.Flagged() {
+		t.Error("report not flagged")
+	}
+
+	clean := NewReport(others[2], tasks, others)
+	if clean.Flagged() {
+		t.Errorf("original solution flagged: %+v", clean.Tasks[0].Matches)
+	}
+}
+
+func TestNewReportUnsubmitted(t *testing.T) {
+	tasks := []*cui.Task{{Id: "sum"}}
+	review := &Review{Id: "t1", Results: []*cui.TaskResult{{TaskId: "sum", ProgLang: "py3", Solution: "x = 1"}}}
+	other := &Review{Id: "t2", Results: []*cui.TaskResult{{TaskId: "sum", ProgLang: "py3", Solution: "x = 1"}}}
+	if report := NewReport(review, tasks, []*Review{other}); report.Flagged() {
+		t.Error("unsubmitted solutions compared")
+	}
+}
@@ -0,0 +1,83 @@
+package frontend
+
+import (
+	"bytes"
+	"github.com/maddyonline/g2/cui"
+	"html/template"
+	"sort"
+	"time"
+)
+
+// Review is what the reviewer dashboard shows about one ticket.
+type Review struct {
+	Id        string
+	Candidate string
+	Problems  []string
+	Status    cui.TicketStatus
+	Shared    bool
+	StartTime time.Time
+	EndTime   time.Time
+	TimeUsed  time.Duration
+	Score     int
+	Pending   bool
+	ProgLangs []string
+	Results   []*cui.TaskResult
+	History   []*cui.Submission
+	Events    []*cui.Event
+}
+
+func NewReview(session *cui.Session, tasks []*cui.Task) *Review {
+	session.Refresh()
+	results := session.Results(tasks)
+	r := &Review{
+		Shared:   session.Shared(),
+		TimeUsed: session.TimeUsed(),
+		Score:    cui.Score(results),
+		Pending:  cui.Pending(results),
+		Results:  results,
+		History:  session.History(),
+		Events:   session.EventLog(),
+	}
+	session.Lock()
+	r.Id = session.Ticket.Id
+	r.Candidate = session.Candidate
+	r.Problems = session.Ticket.Options.TaskNames
+	r.Status = session.Status
+	r.StartTime = session.StartTime
+	r.EndTime = session.EndTime
+	session.Unlock()
+
+	seen := map[string]bool{}
+	for _, res := range results {
+		if res.ProgLang != "" && !seen[res.ProgLang] {
+			seen[res.ProgLang] = true
+			r.ProgLangs = append(r.ProgLangs, res.ProgLang)
+		}
+	}
+	sort.Strings(r.ProgLangs)
+	return r
+}
+
+// byEndTime orders reviews most recently finished first.
+type byEndTime []*Review
+
+func (a byEndTime) Len() int           { return len(a) }
+func (a byEndTime) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byEndTime) Less(i, j int) bool { return a[i].EndTime.After(a[j].EndTime) }
+
+func ReviewList(tmpl *template.Template, reviews []*Review) ([]byte, error) {
+	sort.Sort(byEndTime(reviews))
+	var b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&b, "review_list", reviews); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func ReviewTicket(tmpl *template.Template, review *Review) ([]byte, error) {
+	var b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&b, "review_ticket", review); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
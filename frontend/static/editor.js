@@ -0,0 +1,97 @@
+// Problem editor: collects the form into a draft, previews the statement as
+// it is typed and saves it.
+
+function collectDraft() {
+  var tags = $.map($('#tags').val().split(','), function(tag) {
+    tag = $.trim(tag);
+    return tag === '' ? null : tag;
+  });
+  var draft = {
+    name: $('#editor').data('name'),
+    title: $('#title').val(),
+    description: $('#description').val(),
+    templates: {},
+    examples: [],
+    tests: [],
+    meta: $.extend({}, DRAFT.meta, {
+      tags: tags,
+      difficulty: $('#difficulty').val()
+    })
+  };
+  $('textarea.template').each(function(i, el) {
+    if ($.trim($(el).val()) !== '') {
+      draft.templates[$(el).data('lang')] = $(el).val();
+    }
+  });
+  $('#tests .test').each(function(i, el) {
+    var test = {input: $(el).find('.input').val(), output: $(el).find('.output').val()};
+    if ($(el).find('.kind').val() === 'example') {
+      draft.examples.push(test);
+    } else {
+      draft.tests.push(test);
+    }
+  });
+  return draft;
+}
+
+function post(path, draft, success, error) {
+  $.ajax({
+    url: '/editor/' + encodeURIComponent(draft.name) + path,
+    method: 'POST',
+    contentType: 'application/json',
+    data: JSON.stringify(draft),
+    headers: {'X-CSRF-Token': $('#editor').data('csrf-token')},
+    success: success,
+    error: error
+  });
+}
+
+function showStatus(kind, title, lines) {
+  var el = $('<div class="alert">').addClass('alert-' + kind).text(title);
+  if (lines && lines.length) {
+    var list = $('<ul>');
+    $.each(lines, function(i, line) {
+      list.append($('<li>').text(line));
+    });
+    el.append(list);
+  }
+  $('#status').empty().append(el);
+}
+
+var previewTimer = null;
+
+function schedulePreview() {
+  clearTimeout(previewTimer);
+  previewTimer = setTimeout(function() {
+    post('/preview', collectDraft(), function(data) {
+      // The server sanitizes the preview like candidate statements.
+      $('#preview').html(data.html);
+    });
+  }, 300);
+}
+
+function save() {
+  post('', collectDraft(), function(data) {
+    var saved = data.version ? 'Saved as version ' + data.version : 'Saved';
+    if (data.warnings && data.warnings.length) {
+      showStatus('warning', saved + ', but:', data.warnings);
+    } else {
+      showStatus('success', saved + '.');
+    }
+  }, function(xhr) {
+    var data = xhr.responseJSON || {};
+    showStatus('danger', 'Not saved:', data.errors || [data.message || xhr.statusText]);
+  });
+}
+
+$(document).ready(function() {
+  $('#editor').on('input change', 'input, textarea, select', schedulePreview);
+  $('#add-test').click(function() {
+    $('#tests').append($('#test-template .test').clone());
+  });
+  $('#tests').on('click', '.remove-test', function() {
+    $(this).closest('.test').remove();
+    schedulePreview();
+  });
+  $('#save').click(save);
+});
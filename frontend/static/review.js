@@ -0,0 +1,17 @@
+var ACE_MODES = {
+  'c': 'c_cpp',
+  'cpp': 'c_cpp',
+  'py2': 'python',
+  'py3': 'python',
+  'go': 'golang',
+  'js': 'javascript'
+};
+
+$(document).ready(function() {
+  ace.config.set('basePath', '/static/cui/vendor/ace-src-noconflict');
+  var highlight = ace.require('ace/ext/static_highlight');
+  $('pre.solution').each(function(i, el) {
+    var mode = ACE_MODES[$(el).data('prg-lang')] || 'plain_text';
+    highlight(el, {mode: 'ace/mode/' + mode, theme: 'ace/theme/chrome', showGutter: true});
+  });
+});
@@ -0,0 +1,99 @@
+{{define "editor"}}
+<!DOCTYPE html>
+<html>
+  <head>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <link rel="stylesheet" href="/static/vendor/bootstrap/css/bootstrap.min.css">
+    <script src="/static/vendor/jquery/jquery.min.js"></script>
+    <script src="/static/vendor/bootstrap/js/bootstrap.min.js"></script>
+    <script>var DRAFT = {{.Draft}};</script>
+    <script src="/static/editor.js"></script>
+    <style>
+      textarea.code { font-family: monospace; }
+      #preview { border-left: 1px solid #ddd; padding-left: 15px; }
+    </style>
+  </head>
+  <body>
+    <div class="container-fluid" id="editor" data-name="{{.Draft.Name}}" data-csrf-token="{{.CSRFToken}}">
+      <p><a href="/editor">&larr; All problems</a></p>
+      <h2>{{.Draft.Name}}{{if .New}} <span class="label label-info">new</span>{{end}}</h2>
+      <div class="row">
+        <div class="col-md-6">
+          <div class="form-group">
+            <label for="title">Title</label>
+            <input type="text" class="form-control" id="title" value="{{.Draft.Title}}">
+          </div>
+          <div class="form-group">
+            <label for="description">Description (markdown)</label>
+            <textarea class="form-control" id="description" rows="14">{{.Draft.Description}}</textarea>
+          </div>
+          <div class="row">
+            <div class="form-group col-sm-3">
+              <label for="difficulty">Difficulty</label>
+              <select class="form-control" id="difficulty">
+                <option value=""></option>
+                {{range .Difficulties}}
+                <option value="{{.}}"{{if eq . $.Draft.Meta.Difficulty}} selected{{end}}>{{.}}</option>
+                {{end}}
+              </select>
+            </div>
+            <div class="form-group col-sm-9">
+              <label for="tags">Tags</label>
+              <input type="text" class="form-control" id="tags" placeholder="graphs, bfs" value="{{range $i, $t := .Draft.Meta.Tags}}{{if $i}}, {{end}}{{$t}}{{end}}">
+            </div>
+          </div>
+
+          <h3>Starter templates</h3>
+          <p class="help-block">Leave a language empty to not offer it.</p>
+          {{range .Languages}}
+          <div class="form-group">
+            <label>{{.}}</label>
+            <textarea class="form-control code template" data-lang="{{.}}" rows="8">{{index $.Draft.Templates .}}</textarea>
+          </div>
+          {{end}}
+
+          <h3>Tests</h3>
+          <p class="help-block">Examples are shown in the statement; hidden tests are not.</p>
+          <div id="tests">
+            {{range .Tests}}{{template "editor_test" .}}{{end}}
+          </div>
+          <div id="test-template" class="hidden">{{template "editor_test" .BlankTest}}</div>
+          <p><button type="button" class="btn btn-default" id="add-test">Add test</button></p>
+
+          <div id="status"></div>
+          <p><button type="button" class="btn btn-primary" id="save">Save</button></p>
+        </div>
+        <div class="col-md-6" id="preview">{{.Preview}}</div>
+      </div>
+    </div>
+  </body>
+</html>
+{{end}}
+
+{{define "editor_test"}}
+<div class="panel panel-default test">
+  <div class="panel-body">
+    <div class="row">
+      <div class="col-sm-3">
+        <select class="form-control kind">
+          <option value="example"{{if .Example}} selected{{end}}>example</option>
+          <option value="hidden"{{if not .Example}} selected{{end}}>hidden</option>
+        </select>
+      </div>
+      <div class="col-sm-9 text-right">
+        <button type="button" class="btn btn-link remove-test">Remove</button>
+      </div>
+    </div>
+    <div class="row">
+      <div class="col-sm-6">
+        <label>Input</label>
+        <textarea class="form-control code input" rows="4">{{.Input}}</textarea>
+      </div>
+      <div class="col-sm-6">
+        <label>Expected output</label>
+        <textarea class="form-control code output" rows="4">{{.Output}}</textarea>
+      </div>
+    </div>
+  </div>
+</div>
+{{end}}
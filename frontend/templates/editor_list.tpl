@@ -0,0 +1,46 @@
+{{define "editor_list"}}
+<!DOCTYPE html>
+<html>
+  <head>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <link rel="stylesheet" href="/static/vendor/bootstrap/css/bootstrap.min.css">
+    <script src="/static/vendor/jquery/jquery.min.js"></script>
+    <script src="/static/vendor/bootstrap/js/bootstrap.min.js"></script>
+  </head>
+  <body>
+    <div class="container">
+      <h2>Problems</h2>
+      <form class="form-inline" method="get" action="/editor">
+        <div class="form-group">
+          <input type="text" class="form-control" name="new" placeholder="new-problem-name" required>
+        </div>
+        <button type="submit" class="btn btn-primary">New problem</button>
+      </form>
+      {{if .}}
+      <table class="table table-striped table-hover">
+        <thead>
+          <tr>
+            <th>Name</th>
+            <th>Title</th>
+            <th>Version</th>
+            <th></th>
+          </tr>
+        </thead>
+        <tbody>
+        {{range .}}
+          <tr>
+            <td><a href="/editor/{{.Name}}">{{.Name}}</a></td>
+            <td>{{.Title}}</td>
+            <td><code>{{.Version}}</code></td>
+            <td>{{if .Error}}<span class="label label-danger" title="{{.Error}}">does not load</span>{{end}}</td>
+          </tr>
+        {{end}}
+        </tbody>
+      </table>
+      {{else}}
+      <p>There are no problems yet.</p>
+      {{end}}
+    </div>
+  </body>
+</html>
+{{end}}
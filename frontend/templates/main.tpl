@@ -12,13 +12,39 @@
     <div class="container">
       <h2>Problems list</h2>
       <p><strong>Note:</strong> This a work in progress web application.  For details visit http://madhavjha.com.</p>
+      <form class="form-inline" method="get" action="/">
+        <input type="search" class="form-control" name="q" value="{{.Text}}" placeholder="Search problems">
+        <select class="form-control" name="tag">
+          <option value="">All tags</option>
+          {{range .Tags}}<option value="{{.}}" {{if eq . $.Tag}}selected{{end}}>{{.}}</option>{{end}}
+        </select>
+        <select class="form-control" name="difficulty">
+          <option value="">Any difficulty</option>
+          {{range .Difficulties}}<option value="{{.}}" {{if eq . $.Difficulty}}selected{{end}}>{{.}}</option>{{end}}
+        </select>
+        <select class="form-control" name="lang">
+          <option value="">Any language</option>
+          {{range .ProgLangs}}<option value="{{.}}" {{if eq . $.ProgLang}}selected{{end}}>{{.}}</option>{{end}}
+        </select>
+        <button type="submit" class="btn btn-default">Search</button>
+      </form>
+      <p class="text-muted">{{.Total}} problem{{if ne .Total 1}}s{{end}}</p>
       <div class="panel-group" id="accordion">
-      	{{template "problems_list" .}}
+      	{{template "problems_list" .Entries}}
       </div>
+      {{if gt .Pages 1}}
+      <nav>
+        <ul class="pagination">
+          {{range .PageNumbers}}
+          <li {{if eq . $.Page}}class="active"{{end}}><a href="{{$.URL .}}">{{.}}</a></li>
+          {{end}}
+        </ul>
+      </nav>
+      {{end}}
       <div id="parent">
         <a id="child"></a>
       </div>
     </div>
   </body>
 </html>
-{{end}}
\ No newline at end of file
+{{end}}
@@ -4,10 +4,15 @@
 		  <div class="panel-heading">
 		    <h4 class="panel-title">
 		      <a data-toggle="collapse" data-parent="#accordion" href="#collapse{{$i}}">{{$e.Title}}</a>
+		      {{if $e.Difficulty}}<span class="label label-default">{{$e.Difficulty}}</span>{{end}}
+		      {{range $e.Tags}}<a class="label label-info" href="/?tag={{.}}">{{.}}</a> {{end}}
 		    </h4>
 		  </div>
 		  <div id="collapse{{$i}}" class="panel-collapse collapse {{if eq $i 0}}in{{end}}">
-		    <div class="panel-body">{{$e.ShortDesc}}</div>
+		    <div class="panel-body">
+		      {{$e.ShortDesc}}
+		      {{if $e.ProgLangs}}<p class="text-muted">Languages: {{range $j, $l := $e.ProgLangs}}{{if $j}}, {{end}}{{$l}}{{end}}</p>{{end}}
+		    </div>
 		    <div>
 		      <input type="button" class="newproblem" name="{{$e.Name}}" value="Attempt {{$e.Name}}"/>
 		    </div>
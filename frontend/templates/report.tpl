@@ -0,0 +1,98 @@
+{{define "report"}}
+<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="utf-8">
+    <title>Assessment report: {{if .Candidate}}{{.Candidate}}{{else}}{{.Id}}{{end}}</title>
+    <style>
+      body { font-family: "Helvetica Neue", Helvetica, Arial, sans-serif; font-size: 14px; color: #333; max-width: 900px; margin: 2em auto; padding: 0 1em; }
+      h1 { font-size: 24px; margin-bottom: 0; }
+      h2 { font-size: 18px; border-bottom: 1px solid #ddd; padding-bottom: 4px; margin-top: 2em; }
+      table { border-collapse: collapse; width: 100%; margin: 1em 0; }
+      th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #eee; vertical-align: top; }
+      pre { background: #f7f7f7; border: 1px solid #ddd; padding: 8px; font-size: 12px; white-space: pre-wrap; word-wrap: break-word; }
+      .meta { color: #777; }
+      .ok { color: #3c763d; }
+      .failed { color: #a94442; }
+      .flag { background: #f2dede; border: 1px solid #ebccd1; color: #a94442; padding: 8px; }
+      .description { border-left: 3px solid #ddd; padding-left: 1em; }
+      @media print {
+        body { margin: 0; max-width: none; font-size: 11pt; }
+        h2 { page-break-after: avoid; }
+        .task { page-break-before: always; }
+        pre { page-break-inside: avoid; font-size: 9pt; }
+        a { color: inherit; text-decoration: none; }
+      }
+    </style>
+  </head>
+  <body>
+    <h1>{{if .Candidate}}{{.Candidate}}{{else}}Anonymous candidate{{end}}</h1>
+    <p class="meta">Ticket {{.Id}} &middot; generated {{.Generated.Format "2006-01-02 15:04"}}</p>
+
+    <h2>Summary</h2>
+    <table>
+      <tr><th>Status</th><td>{{.Status}}</td></tr>
+      <tr><th>Score</th><td>{{.Score}}%{{if .Pending}} (verdicts pending){{end}}</td></tr>
+      <tr><th>Time used</th><td>{{.TimeUsed}}</td></tr>
+      {{if not .StartTime.IsZero}}<tr><th>Started</th><td>{{.StartTime.Format "2006-01-02 15:04:05"}}</td></tr>{{end}}
+      {{if not .EndTime.IsZero}}<tr><th>Finished</th><td>{{.EndTime.Format "2006-01-02 15:04:05"}}</td></tr>{{end}}
+    </table>
+    {{if or .Flagged .Shared}}
+    <p class="flag">
+      {{if .Flagged}}Some solutions are very similar to other candidates' solutions.{{end}}
+      {{if .Shared}}The test was opened from more than one machine.{{end}}
+    </p>
+    {{end}}
+
+    <table>
+      <thead><tr><th>Task</th><th>Language</th><th>Score</th><th>Time spent</th><th>Similar solutions</th></tr></thead>
+      <tbody>
+      {{range .Tasks}}
+        <tr>
+          <td>{{.TaskId}}</td>
+          <td>{{.ProgLang}}</td>
+          <td>{{if .Pending}}pending{{else}}{{.Score}}%{{end}}</td>
+          <td>{{.TimeSpent}}</td>
+          <td>{{len .Matches}}</td>
+        </tr>
+      {{end}}
+      </tbody>
+    </table>
+
+    {{range .Tasks}}
+    <div class="task">
+      <h2>{{.TaskId}}</h2>
+      <div class="description">{{.Description}}</div>
+
+      <h3>Verdict</h3>
+      {{if .Verdict}}
+      <table>
+        {{range .Verdict.Tests}}
+        <tr>
+          <th>{{.Name}}</th>
+          <td class="{{if .OK}}ok{{else}}failed{{end}}">{{if .OK}}OK{{else}}FAILED{{end}}</td>
+        </tr>
+        {{end}}
+      </table>
+      {{else if .Pending}}
+      <p>The verdict is still pending.</p>
+      {{else}}
+      <p>This task was never judged.</p>
+      {{end}}
+
+      {{if .Matches}}
+      <h3>Similar solutions</h3>
+      <table>
+        {{range .Matches}}
+        <tr><td>{{if .Candidate}}{{.Candidate}}{{else}}anonymous{{end}} ({{.TicketId}})</td><td>{{.Similarity}}% similar</td></tr>
+        {{end}}
+      </table>
+      {{end}}
+
+      <h3>Final solution ({{.ProgLang}})</h3>
+      <pre>{{.Solution}}</pre>
+    </div>
+    {{end}}
+  </body>
+</html>
+{{end}}
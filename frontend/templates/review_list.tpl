@@ -0,0 +1,49 @@
+{{define "review_list"}}
+<!DOCTYPE html>
+<html>
+  <head>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <link rel="stylesheet" href="/static/vendor/bootstrap/css/bootstrap.min.css">
+    <script src="/static/vendor/jquery/jquery.min.js"></script>
+    <script src="/static/vendor/bootstrap/js/bootstrap.min.js"></script>
+  </head>
+  <body>
+    <div class="container">
+      <h2>Completed assessments</h2>
+      {{if .}}
+      <table class="table table-striped table-hover">
+        <thead>
+          <tr>
+            <th>Candidate</th>
+            <th>Problems</th>
+            <th>Score</th>
+            <th>Time used</th>
+            <th>Language</th>
+            <th>Finished</th>
+            <th></th>
+          </tr>
+        </thead>
+        <tbody>
+        {{range .}}
+          <tr>
+            <td><a href="/review/{{.Id}}">{{if .Candidate}}{{.Candidate}}{{else}}anonymous{{end}}</a></td>
+            <td>{{range $i, $p := .Problems}}{{if $i}}, {{end}}{{$p}}{{end}}</td>
+            <td>{{.Score}}%{{if .Pending}} (pending){{end}}</td>
+            <td>{{.TimeUsed}}</td>
+            <td>{{range $i, $l := .ProgLangs}}{{if $i}}, {{end}}{{$l}}{{end}}</td>
+            <td>{{.EndTime.Format "2006-01-02 15:04"}}</td>
+            <td>
+              {{if eq .Status "timed_out"}}<span class="label label-warning">timed out</span>{{end}}
+              {{if .Shared}}<span class="label label-danger">shared</span>{{end}}
+            </td>
+          </tr>
+        {{end}}
+        </tbody>
+      </table>
+      {{else}}
+      <p>No assessment has been completed yet.</p>
+      {{end}}
+    </div>
+  </body>
+</html>
+{{end}}
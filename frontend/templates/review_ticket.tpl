@@ -0,0 +1,96 @@
+{{define "review_ticket"}}
+<!DOCTYPE html>
+<html>
+  <head>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <link rel="stylesheet" href="/static/vendor/bootstrap/css/bootstrap.min.css">
+    <script src="/static/vendor/jquery/jquery.min.js"></script>
+    <script src="/static/vendor/bootstrap/js/bootstrap.min.js"></script>
+    <script src="/static/cui/vendor/ace-src-noconflict/ace.js"></script>
+    <script src="/static/cui/vendor/ace-src-noconflict/ext-static_highlight.js"></script>
+    <script src="/static/review.js"></script>
+  </head>
+  <body>
+    <div class="container">
+      <p>
+        <a href="/review">&larr; All assessments</a>
+        <a class="btn btn-default btn-sm pull-right" href="/api/v1/tickets/{{.Id}}/report">Download report</a>
+      </p>
+      <h2>{{if .Candidate}}{{.Candidate}}{{else}}Anonymous candidate{{end}}</h2>
+      <dl class="dl-horizontal">
+        <dt>Ticket</dt><dd>{{.Id}}</dd>
+        <dt>Status</dt><dd>{{.Status}}{{if .Shared}} <span class="label label-danger">shared</span>{{end}}</dd>
+        <dt>Score</dt><dd>{{.Score}}%{{if .Pending}} (verdicts pending){{end}}</dd>
+        <dt>Time used</dt><dd>{{.TimeUsed}}</dd>
+        {{if not .StartTime.IsZero}}<dt>Started</dt><dd>{{.StartTime.Format "2006-01-02 15:04:05"}}</dd>{{end}}
+        {{if not .EndTime.IsZero}}<dt>Finished</dt><dd>{{.EndTime.Format "2006-01-02 15:04:05"}}</dd>{{end}}
+      </dl>
+
+      {{range .Results}}
+      <div class="panel panel-default">
+        <div class="panel-heading">
+          <h4 class="panel-title">{{.TaskId}} &middot; {{.ProgLang}} &middot; {{if .Pending}}pending{{else}}{{.Score}}%{{end}}</h4>
+        </div>
+        <div class="panel-body">
+          {{if .Verdict}}
+          <table class="table table-condensed">
+            <thead><tr><th>Test</th><th>Verdict</th><th>Output</th></tr></thead>
+            <tbody>
+            {{range .Verdict.Tests}}
+              <tr class="{{if .OK}}success{{else}}danger{{end}}">
+                <td>{{.Name}}</td>
+                <td>{{if .OK}}OK{{else}}FAILED{{end}}</td>
+                <td><pre>{{.Message}}</pre></td>
+              </tr>
+            {{end}}
+            </tbody>
+          </table>
+          {{else if .Pending}}
+          <p>The verdict is still pending.</p>
+          {{else}}
+          <p>This task was never judged.</p>
+          {{end}}
+          <pre class="solution" data-prg-lang="{{.ProgLang}}">{{.Solution}}</pre>
+        </div>
+      </div>
+      {{end}}
+
+      <h3>Solution history</h3>
+      {{if .History}}
+      <table class="table table-condensed">
+        <thead><tr><th>Time</th><th>Task</th><th>Action</th><th>Language</th><th>Verdict</th><th></th></tr></thead>
+        <tbody>
+        {{range $i, $s := .History}}
+          <tr>
+            <td>{{$s.Time.Format "15:04:05"}}</td>
+            <td>{{$s.TaskId}}</td>
+            <td>{{$s.Mode}}</td>
+            <td>{{$s.ProgLang}}</td>
+            <td>{{if $s.Verdict}}{{if $s.Verdict.Passed}}passed{{else}}failed{{end}}{{else}}pending{{end}}</td>
+            <td><a data-toggle="collapse" href="#history{{$i}}">code</a></td>
+          </tr>
+          <tr id="history{{$i}}" class="collapse">
+            <td colspan="6"><pre class="solution" data-prg-lang="{{$s.ProgLang}}">{{$s.Solution}}</pre></td>
+          </tr>
+        {{end}}
+        </tbody>
+      </table>
+      {{else}}
+      <p>No solution was ever run.</p>
+      {{end}}
+
+      {{if .Events}}
+      <h3>Browser events</h3>
+      <table class="table table-condensed">
+        <thead><tr><th>Time</th><th>Event</th><th>IP</th><th>User agent</th><th>Requests</th></tr></thead>
+        <tbody>
+        {{range .Events}}
+          <tr><td>{{.Time.Format "15:04:05"}}</td><td>{{.Kind}}{{if .By}} by {{.By}}{{end}}</td><td>{{.IP}}</td><td>{{.UserAgent}}</td><td>{{if .Count}}{{.Count}}, last {{.Seen.Format "15:04:05"}}{{end}}</td></tr>
+        {{end}}
+        </tbody>
+      </table>
+      {{end}}
+    </div>
+  </body>
+</html>
+{{end}}
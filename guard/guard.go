@@ -0,0 +1,147 @@
+package guard
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/labstack/echo"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type ErrOtherBrowser struct{}
+
+func (e ErrOtherBrowser) Error() string {
+	return "This ticket is bound to another browser"
+}
+
+type ErrInvalidCSRF struct{}
+
+func (e ErrInvalidCSRF) Error() string {
+	return "Invalid CSRF token"
+}
+
+// Signer signs short values, such as ticket ids, so that they can be handed
+// to the candidate's browser and trusted when they come back.
+type Signer struct {
+	key []byte
+	// Message, if not nil, words the errors the middlewares reject requests
+	// with, such as in the candidate's language.
+	Message func(c echo.Context, err error) string
+}
+
+func NewSigner(key []byte) *Signer {
+	return &Signer{key: key}
+}
+
+func (s *Signer) reject(c echo.Context, err error) error {
+	msg := err.Error()
+	if s.Message != nil {
+		msg = s.Message(c, err)
+	}
+	return echo.NewHTTPError(http.StatusForbidden, msg)
+}
+
+func (s *Signer) mac(value string) string {
+	m := hmac.New(sha256.New, s.key)
+	m.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+func (s *Signer) Sign(value string) string {
+	return value + "." + s.mac(value)
+}
+
+// Verify returns the value of a string produced by Sign, and whether the
+// signature matched.
+func (s *Signer) Verify(signed string) (string, bool) {
+	i := strings.LastIndex(signed, ".")
+	if i < 0 {
+		return "", false
+	}
+	value, mac := signed[:i], signed[i+1:]
+	if !hmac.Equal([]byte(mac), []byte(s.mac(value))) {
+		return "", false
+	}
+	return value, true
+}
+
+// SetCookie stores a signed value in an HttpOnly cookie.
+func (s *Signer) SetCookie(c echo.Context, name, value string, maxAge time.Duration) {
+	cookie := new(echo.Cookie)
+	cookie.SetName(name)
+	cookie.SetValue(s.Sign(value))
+	cookie.SetPath("/")
+	cookie.SetHTTPOnly(true)
+	cookie.SetSecure(c.Request().IsTLS())
+	cookie.SetExpires(time.Now().Add(maxAge))
+	c.SetCookie(cookie)
+}
+
+// Cookie returns the value of a cookie set by SetCookie if its signature is
+// valid.
+func (s *Signer) Cookie(c echo.Context, name string) (string, bool) {
+	cookie, err := c.Cookie(name)
+	if err != nil {
+		return "", false
+	}
+	return s.Verify(cookie.Value())
+}
+
+// RequireCookie rejects requests whose signed cookie does not carry the
+// ticket id they are about, as extracted by ticket. onReject, if not nil,
+// is told about every rejected request.
+func (s *Signer) RequireCookie(name string, ticket func(echo.Context) string, onReject func(c echo.Context, ticketId string)) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ticketId := ticket(c)
+			if bound, ok := s.Cookie(c, name); ok && bound == ticketId {
+				return next(c)
+			}
+			if onReject != nil {
+				onReject(c, ticketId)
+			}
+			return s.reject(c, ErrOtherBrowser{})
+		}
+	}
+}
+
+const (
+	CSRFHeader = "X-CSRF-Token"
+	CSRFField  = "csrf_token"
+)
+
+// CSRFToken is the token pages about id (a ticket or an invite) embed and
+// must send back with every POST. It is derived from the key, so nothing
+// needs to be stored.
+func (s *Signer) CSRFToken(id string) string {
+	return s.mac("csrf:" + id)
+}
+
+// ValidCSRFToken reports whether token is the CSRF token for id.
+func (s *Signer) ValidCSRFToken(id, token string) bool {
+	return token != "" && hmac.Equal([]byte(token), []byte(s.CSRFToken(id)))
+}
+
+// CSRF rejects state-changing requests that do not carry the CSRF token for
+// the id extracted by key, either in the X-CSRF-Token header or in the
+// csrf_token form field.
+func (s *Signer) CSRF(key func(echo.Context) string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			switch c.Request().Method() {
+			case echo.GET, echo.HEAD, echo.OPTIONS:
+				return next(c)
+			}
+			token := c.Request().Header().Get(CSRFHeader)
+			if token == "" {
+				token = c.FormValue(CSRFField)
+			}
+			if !s.ValidCSRFToken(key(c), token) {
+				return s.reject(c, ErrInvalidCSRF{})
+			}
+			return next(c)
+		}
+	}
+}
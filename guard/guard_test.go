@@ -0,0 +1,120 @@
+package guard
+
+import (
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/test"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSigner(t *testing.T) {
+	s := NewSigner([]byte("key"))
+	signed := s.Sign("ticket-1")
+	if v, ok := s.Verify(signed); !ok || v != "ticket-1" {
+		t.Errorf("Verify(%q): got %q, %v", signed, v, ok)
+	}
+	for _, bad := range []string{
+		"",
+		"ticket-1",
+		"ticket-2" + signed[len("ticket-1"):],
+		signed + "x",
+		NewSigner([]byte("other")).Sign("ticket-1"),
+	} {
+		if _, ok := s.Verify(bad); ok {
+			t.Errorf("Verify(%q): expected failure", bad)
+		}
+	}
+}
+
+func TestRequireCookie(t *testing.T) {
+	s := NewSigner([]byte("key"))
+	ticket := func(c echo.Context) string { return c.QueryParam("ticket") }
+	rejected := []string{}
+	onReject := func(c echo.Context, ticketId string) { rejected = append(rejected, ticketId) }
+	h := s.RequireCookie("session", ticket, onReject)(func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	e := echo.New()
+	for _, tc := range []struct {
+		ticket string
+		cookie string
+		code   int
+	}{
+		{"t1", "session=" + s.Sign("t1"), http.StatusOK},
+		{"t1", "", http.StatusForbidden},
+		{"t2", "session=" + s.Sign("t1"), http.StatusForbidden},
+		{"t1", "session=t1", http.StatusForbidden},
+	} {
+		req := test.NewRequest(echo.POST, "/chk/save?ticket="+tc.ticket, nil)
+		if tc.cookie != "" {
+			req.Header().Set("Cookie", tc.cookie)
+		}
+		c := e.NewContext(req, test.NewResponseRecorder())
+		code := http.StatusOK
+		if err := h(c); err != nil {
+			code = err.(*echo.HTTPError).Code
+		}
+		if code != tc.code {
+			t.Errorf("ticket %s, cookie %q: got %d, want %d", tc.ticket, tc.cookie, code, tc.code)
+		}
+	}
+	if len(rejected) != 3 {
+		t.Errorf("onReject called for %v", rejected)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	s := NewSigner([]byte("key"))
+	h := s.CSRF(func(c echo.Context) string { return "t1" })(func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+	c := echo.New().NewContext(test.NewRequest(echo.POST, "/chk/save", nil), test.NewResponseRecorder())
+	if err := h(c); err.(*echo.HTTPError).Message != (ErrInvalidCSRF{}).Error() {
+		t.Errorf("got %v", err)
+	}
+	s.Message = func(c echo.Context, err error) string { return "translated" }
+	if err := h(c); err.(*echo.HTTPError).Message != "translated" {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	s := NewSigner([]byte("key"))
+	h := s.CSRF(func(c echo.Context) string { return c.FormValue("ticket") })(func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	e := echo.New()
+	for _, tc := range []struct {
+		name   string
+		method string
+		header string
+		form   url.Values
+		code   int
+	}{
+		{"header", echo.POST, s.CSRFToken("t1"), url.Values{"ticket": {"t1"}}, http.StatusOK},
+		{"form field", echo.POST, "", url.Values{"ticket": {"t1"}, CSRFField: {s.CSRFToken("t1")}}, http.StatusOK},
+		{"GET needs no token", echo.GET, "", url.Values{"ticket": {"t1"}}, http.StatusOK},
+		{"missing", echo.POST, "", url.Values{"ticket": {"t1"}}, http.StatusForbidden},
+		{"other ticket", echo.POST, s.CSRFToken("t2"), url.Values{"ticket": {"t1"}}, http.StatusForbidden},
+		{"other key", echo.POST, NewSigner([]byte("x")).CSRFToken("t1"), url.Values{"ticket": {"t1"}}, http.StatusForbidden},
+		{"garbage", echo.POST, "garbage", url.Values{"ticket": {"t1"}}, http.StatusForbidden},
+	} {
+		req := test.NewRequest(tc.method, "/chk/verify", strings.NewReader(tc.form.Encode()))
+		req.Header().Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		if tc.header != "" {
+			req.Header().Set(CSRFHeader, tc.header)
+		}
+		c := e.NewContext(req, test.NewResponseRecorder())
+		code := http.StatusOK
+		if err := h(c); err != nil {
+			code = err.(*echo.HTTPError).Code
+		}
+		if code != tc.code {
+			t.Errorf("%s: got %d, want %d", tc.name, code, tc.code)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"github.com/labstack/echo"
+	"github.com/maddyonline/g2/health"
+	"github.com/maddyonline/umpire"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+)
+
+// readiness answers /readyz. Running a program takes a container, so the
+// executor is checked at most once a minute, and writing to the store at
+// most every few seconds.
+var readiness = health.New(15 * time.Second)
+
+type ErrNoProblems struct {
+	Dir string
+}
+
+func (e ErrNoProblems) Error() string {
+	return fmt.Sprintf("No problems loaded from %s", e.Dir)
+}
+
+type ErrExecutor struct {
+	Status  umpire.StatusT
+	Details string
+}
+
+func (e ErrExecutor) Error() string {
+	return fmt.Sprintf("Running a program: status %q: %s", e.Status, e.Details)
+}
+
+type ErrShuttingDown struct{}
+
+func (e ErrShuttingDown) Error() string {
+	return "Shutting down"
+}
+
+func checkProblems() error {
+	if _, err := os.Stat(problemSet.Dir); err != nil {
+		return err
+	}
+	if len(problemSet.Problems()) == 0 {
+		return ErrNoProblems{problemSet.Dir}
+	}
+	return nil
+}
+
+// checkExecutor runs a trivial program against the first problem, the way
+// candidates' solutions are run. If it hangs, its container runs until the
+// executor's own limits stop it; readiness does not start another meanwhile.
+func checkExecutor() error {
+	cli.Lock()
+	ids := []string{}
+	for id := range cli.ProbsList {
+		ids = append(ids, id)
+	}
+	agent := cli.Agent
+	cli.Unlock()
+	if len(ids) == 0 {
+		return ErrNoProblems{problemSet.Dir}
+	}
+	sort.Strings(ids)
+	out := umpire.RunDefault(agent, &umpire.Payload{
+		Problem:  &umpire.Problem{ids[0]},
+		Language: "python",
+		Files:    []*umpire.InMemoryFile{{Name: "main.py", Content: "print(1)\n"}},
+		Stdin:    "1\n",
+	})
+	if out.Status != umpire.Pass && out.Status != umpire.Fail {
+		return ErrExecutor{out.Status, out.Details}
+	}
+	return nil
+}
+
+func checkStorage() error {
+	return db.Put("health", "readyz", map[string]time.Time{"checked": time.Now()})
+}
+
+func checkAccepting() error {
+	shutdownLock.RLock()
+	defer shutdownLock.RUnlock()
+	if shuttingDown {
+		return ErrShuttingDown{}
+	}
+	return nil
+}
+
+// addHealthHandlers serves /healthz, answering while the process runs, and
+// /readyz, answering 200 only while the server can take candidates.
+func addHealthHandlers(e *echo.Echo) {
+	readiness.Add("problems", 0, checkProblems)
+	readiness.Add("executor", time.Minute, checkExecutor)
+	readiness.Add("storage", 5*time.Second, checkStorage)
+	readiness.Add("accepting", 0, checkAccepting)
+
+	e.Get("/healthz", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]string{"status": health.OK})
+	})
+	e.Get("/readyz", func(c echo.Context) error {
+		report := readiness.Run()
+		status := http.StatusOK
+		if report.Status != health.OK {
+			status = http.StatusServiceUnavailable
+		}
+		return c.JSON(status, report)
+	})
+}
@@ -0,0 +1,137 @@
+// Package health answers readiness probes: whether the dependencies the
+// server needs to serve candidates, such as the problems, the executor and
+// the store, work. Checks that are slow to run keep their result for a while,
+// so frequent probes do not load the dependency they check.
+package health
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	OK     = "ok"
+	FAILED = "failed"
+)
+
+// Check returns why a dependency does not work, or nil.
+type Check func() error
+
+type ErrTimeout struct {
+	Timeout time.Duration
+}
+
+func (e ErrTimeout) Error() string {
+	return fmt.Sprintf("No answer after %v", e.Timeout)
+}
+
+// Result is the outcome of a check.
+type Result struct {
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs float64   `json:"duration_ms"`
+	Checked    time.Time `json:"checked"`
+}
+
+// Report holds the results of every check. Its Status is OK only if every
+// check passed.
+type Report struct {
+	Status string             `json:"status"`
+	Checks map[string]*Result `json:"checks"`
+}
+
+type entry struct {
+	check Check
+	every time.Duration
+	last  *Result
+	// running is closed when the run started at started ends, and is nil
+	// between runs.
+	running chan struct{}
+	started time.Time
+}
+
+// Checker runs the checks added to it. It is safe for concurrent use.
+type Checker struct {
+	sync.Mutex
+	// Timeout bounds how long a probe waits for a check before failing it.
+	Timeout time.Duration
+	checks  map[string]*entry
+	now     func() time.Time
+}
+
+func New(timeout time.Duration) *Checker {
+	return &Checker{Timeout: timeout, checks: map[string]*entry{}, now: time.Now}
+}
+
+// Add adds the check called name. Its result is kept for every, or it runs
+// on every probe if every is 0.
+func (c *Checker) Add(name string, every time.Duration, check Check) {
+	c.Lock()
+	defer c.Unlock()
+	c.checks[name] = &entry{check: check, every: every}
+}
+
+// start runs the check of e in the background. The caller holds the lock.
+func (c *Checker) start(e *entry) {
+	done := make(chan struct{})
+	e.running, e.started = done, c.now()
+	go func() {
+		err := e.check()
+		c.Lock()
+		defer c.Unlock()
+		res := &Result{Status: OK, DurationMs: c.now().Sub(e.started).Seconds() * 1000, Checked: e.started}
+		if err != nil {
+			res.Status, res.Error = FAILED, err.Error()
+		}
+		e.last, e.running = res, nil
+		close(done)
+	}()
+}
+
+// Run runs the checks whose results are out of date, all at once, and
+// reports the results of every check. A check still running, say for an
+// earlier probe, is not started again but waited for: a hung check holds
+// on to one of whatever it checks with, not one per probe. Checks taking
+// longer than Timeout are reported as failed.
+func (c *Checker) Run() *Report {
+	c.Lock()
+	waits := map[string]chan struct{}{}
+	for name, e := range c.checks {
+		if e.running == nil && (e.last == nil || c.now().Sub(e.last.Checked) >= e.every) {
+			c.start(e)
+		}
+		if e.running != nil {
+			waits[name] = e.running
+		}
+	}
+	c.Unlock()
+
+	timeout := time.After(c.Timeout)
+	for _, done := range waits {
+		select {
+		case <-done:
+		case <-timeout:
+		}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	report := &Report{Status: OK, Checks: map[string]*Result{}}
+	for name, e := range c.checks {
+		res := e.last
+		if done, ok := waits[name]; ok && e.running == done {
+			res = &Result{Status: FAILED, Error: ErrTimeout{c.Timeout}.Error(),
+				DurationMs: c.now().Sub(e.started).Seconds() * 1000, Checked: e.started}
+		}
+		if res == nil {
+			// Added since the checks were started.
+			continue
+		}
+		report.Checks[name] = res
+		if res.Status != OK {
+			report.Status = FAILED
+		}
+	}
+	return report
+}
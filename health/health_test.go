@@ -0,0 +1,91 @@
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	c := New(time.Second)
+	c.Add("store", 0, func() error { return nil })
+	c.Add("executor", 0, func() error { return errors.New("docker is down") })
+	report := c.Run()
+	if report.Status != FAILED {
+		t.Errorf("Status = %q with a failed check", report.Status)
+	}
+	if res := report.Checks["store"]; res.Status != OK || res.Error != "" {
+		t.Errorf("store: %+v", res)
+	}
+	if res := report.Checks["executor"]; res.Status != FAILED || res.Error != "docker is down" {
+		t.Errorf("executor: %+v", res)
+	}
+
+	c = New(time.Second)
+	c.Add("store", 0, func() error { return nil })
+	if report := c.Run(); report.Status != OK {
+		t.Errorf("Status = %q, want %q", report.Status, OK)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	c := New(10 * time.Millisecond)
+	block := make(chan struct{})
+	defer close(block)
+	c.Add("executor", 0, func() error { <-block; return nil })
+	res := c.Run().Checks["executor"]
+	if res.Status != FAILED || res.Error != (ErrTimeout{10 * time.Millisecond}).Error() {
+		t.Errorf("executor: %+v", res)
+	}
+}
+
+func TestEvery(t *testing.T) {
+	now := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	c := New(time.Second)
+	c.now = func() time.Time { return now }
+	runs := 0
+	c.Add("executor", time.Minute, func() error { runs++; return nil })
+	c.Run()
+	now = now.Add(30 * time.Second)
+	c.Run()
+	if runs != 1 {
+		t.Errorf("ran %d times within a minute, want 1", runs)
+	}
+	now = now.Add(time.Minute)
+	c.Run()
+	if runs != 2 {
+		t.Errorf("ran %d times after a minute, want 2", runs)
+	}
+}
+
+func TestHungCheck(t *testing.T) {
+	c := New(10 * time.Millisecond)
+	runs := make(chan struct{}, 10)
+	release := make(chan struct{})
+	c.Add("executor", time.Minute, func() error {
+		runs <- struct{}{}
+		<-release
+		return nil
+	})
+	// Probes while the check hangs neither queue behind it nor start it
+	// again.
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		if res := c.Run().Checks["executor"]; res.Status != FAILED {
+			t.Errorf("probe %d: %+v", i, res)
+		}
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("probe %d took %s", i, d)
+		}
+	}
+	close(release)
+	for i := 0; c.Run().Status != OK; i++ {
+		if i == 100 {
+			t.Fatal("the check never finished")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := len(runs); n != 1 {
+		t.Errorf("ran %d times, want 1", n)
+	}
+}
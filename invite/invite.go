@@ -0,0 +1,161 @@
+package invite
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"github.com/maddyonline/g2/store"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUsed        = errors.New("This invitation has already been used")
+	ErrExpired     = errors.New("This invitation has expired")
+	ErrNotYetValid = errors.New("This invitation is not valid yet")
+)
+
+type Status string
+
+const (
+	PENDING Status = "pending"
+	USED    Status = "used"
+	EXPIRED Status = "expired"
+)
+
+// Invite is a one-time link a recruiter sends to a candidate. Opening it
+// shows the instructions; starting it creates the ticket.
+type Invite struct {
+	Id             string    `json:"id"`
+	Candidate      string    `json:"candidate"`
+	Problems       []string  `json:"problems"`
+	ProgLangs      []string  `json:"prg_langs"`
+	TimeLimit      int       `json:"time_limit"`
+	LanguageServer bool      `json:"language_server"`
+	ValidFrom      time.Time `json:"valid_from"`
+	ValidUntil     time.Time `json:"valid_until"`
+	CreatedBy      string    `json:"created_by"`
+	Created        time.Time `json:"created"`
+	TicketId       string    `json:"ticket_id,omitempty"`
+	Used           time.Time `json:"used"`
+}
+
+func (inv *Invite) Status(now time.Time) Status {
+	switch {
+	case inv.TicketId != "" || !inv.Used.IsZero():
+		return USED
+	case now.After(inv.ValidUntil):
+		return EXPIRED
+	}
+	return PENDING
+}
+
+// Check tells whether the invite can be started at the given time.
+func (inv *Invite) Check(now time.Time) error {
+	switch {
+	case inv.TicketId != "" || !inv.Used.IsZero():
+		return ErrUsed
+	case now.Before(inv.ValidFrom):
+		return ErrNotYetValid
+	case now.After(inv.ValidUntil):
+		return ErrExpired
+	}
+	return nil
+}
+
+type Invites struct {
+	Store *store.Store
+	*sync.Mutex
+}
+
+func New(db *store.Store) *Invites {
+	return &Invites{Store: db, Mutex: &sync.Mutex{}}
+}
+
+func newId() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Create stores a new invite. A zero ValidFrom means "from now on". The
+// ticket and time it was used by start out empty, whatever inv says.
+func (iv *Invites) Create(inv *Invite) (*Invite, error) {
+	if inv.Candidate == "" || len(inv.Problems) == 0 || inv.TimeLimit <= 0 {
+		return nil, errors.New("candidate, problems and time_limit are required")
+	}
+	id, err := newId()
+	if err != nil {
+		return nil, err
+	}
+	inv.Id = id
+	inv.Created = time.Now()
+	inv.TicketId, inv.Used = "", time.Time{}
+	if inv.ValidFrom.IsZero() {
+		inv.ValidFrom = inv.Created
+	}
+	if !inv.ValidUntil.After(inv.ValidFrom) {
+		return nil, errors.New("valid_until must be after valid_from")
+	}
+	if err := iv.Store.Put("invites", inv.Id, inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (iv *Invites) Get(id string) (*Invite, error) {
+	inv := &Invite{}
+	if err := iv.Store.Get("invites", id, inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (iv *Invites) List() ([]*Invite, error) {
+	ids, err := iv.Store.List("invites")
+	if err != nil {
+		return nil, err
+	}
+	list := []*Invite{}
+	for _, id := range ids {
+		inv, err := iv.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, inv)
+	}
+	return list, nil
+}
+
+// Redeem uses up the invite: it is marked as used, then start is called to
+// create the ticket. The invite is given back if start fails. Concurrent
+// calls for the same invite start at most one ticket, and so do calls after
+// the ticket could not be recorded in the invite.
+func (iv *Invites) Redeem(id string, start func(*Invite) (string, error)) (*Invite, error) {
+	iv.Lock()
+	defer iv.Unlock()
+	inv, err := iv.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := inv.Check(time.Now()); err != nil {
+		return nil, err
+	}
+	inv.Used = time.Now()
+	if err := iv.Store.Put("invites", inv.Id, inv); err != nil {
+		return nil, err
+	}
+	ticketId, err := start(inv)
+	if err != nil {
+		inv.Used = time.Time{}
+		iv.Store.Put("invites", inv.Id, inv)
+		return nil, err
+	}
+	inv.TicketId = ticketId
+	if err := iv.Store.Put("invites", inv.Id, inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
@@ -0,0 +1,129 @@
+package invite
+
+import (
+	"errors"
+	"github.com/maddyonline/g2/store"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRedeem(t *testing.T) {
+	dir, err := ioutil.TempDir("", "g2-invite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, _ := store.Open(dir)
+	iv := New(db)
+
+	now := time.Now()
+	inv, err := iv.Create(&Invite{
+		Candidate:  "a@b.com",
+		Problems:   []string{"p1"},
+		TimeLimit:  3600,
+		ValidUntil: now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv.Status(now) != PENDING {
+		t.Errorf("Status: got %s", inv.Status(now))
+	}
+
+	started := 0
+	start := func(inv *Invite) (string, error) {
+		started++
+		return "ticket-1", nil
+	}
+	used, err := iv.Redeem(inv.Id, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used.TicketId != "ticket-1" || used.Status(now) != USED {
+		t.Errorf("Redeem: got %+v", used)
+	}
+	if _, err := iv.Redeem(inv.Id, start); err != ErrUsed {
+		t.Errorf("second Redeem: got %v, want %v", err, ErrUsed)
+	}
+	if started != 1 {
+		t.Errorf("start called %d times", started)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Now()
+	inv := &Invite{ValidFrom: now, ValidUntil: now.Add(time.Hour)}
+	for _, tc := range []struct {
+		at  time.Time
+		err error
+	}{
+		{now.Add(-time.Minute), ErrNotYetValid},
+		{now.Add(time.Minute), nil},
+		{now.Add(2 * time.Hour), ErrExpired},
+	} {
+		if err := inv.Check(tc.at); err != tc.err {
+			t.Errorf("Check(%s): got %v, want %v", tc.at, err, tc.err)
+		}
+	}
+}
+
+func TestCreateIgnoresServerFields(t *testing.T) {
+	dir, err := ioutil.TempDir("", "g2-invite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, _ := store.Open(dir)
+	iv := New(db)
+
+	now := time.Now()
+	inv, err := iv.Create(&Invite{
+		Candidate:  "a@b.com",
+		Problems:   []string{"p1"},
+		TimeLimit:  3600,
+		ValidUntil: now.Add(time.Hour),
+		TicketId:   "ticket-0",
+		Used:       now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv.TicketId != "" || !inv.Used.IsZero() || inv.Status(now) != PENDING {
+		t.Errorf("Create kept server fields: %+v", inv)
+	}
+}
+
+func TestRedeemFailedStart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "g2-invite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, _ := store.Open(dir)
+	iv := New(db)
+
+	inv, err := iv.Create(&Invite{
+		Candidate:  "a@b.com",
+		Problems:   []string{"p1"},
+		TimeLimit:  3600,
+		ValidUntil: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = iv.Redeem(inv.Id, func(inv *Invite) (string, error) {
+		// The invite is used up while the ticket is started.
+		if got, _ := iv.Get(inv.Id); got.Check(time.Now()) != ErrUsed {
+			t.Errorf("invite not marked used before start: %+v", got)
+		}
+		return "", errors.New("no such problem")
+	})
+	if err == nil {
+		t.Fatal("Redeem succeeded with a failing start")
+	}
+	if _, err := iv.Redeem(inv.Id, func(*Invite) (string, error) { return "ticket-1", nil }); err != nil {
+		t.Errorf("invite not given back after a failed start: %v", err)
+	}
+}
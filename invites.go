@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"github.com/labstack/echo"
+	"github.com/labstack/gommon/log"
+	"github.com/maddyonline/g2/auth"
+	"github.com/maddyonline/g2/cui"
+	"github.com/maddyonline/g2/invite"
+	"github.com/maddyonline/g2/logging"
+	"github.com/maddyonline/g2/store"
+	"github.com/maddyonline/g2/webhook"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var invites *invite.Invites
+
+type inviteView struct {
+	*invite.Invite
+	Url    string        `json:"url"`
+	Status invite.Status `json:"status"`
+}
+
+func viewInvite(inv *invite.Invite) *inviteView {
+	return &inviteView{inv, fmt.Sprintf("/invite/%s", inv.Id), inv.Status(time.Now())}
+}
+
+// startInvite creates the ticket an invite stands for.
+func startInvite(inv *invite.Invite) (string, error) {
+	stateLock.Lock()
+	ticket, err := cli.NewTicket(tasks, inv.Problems, inv.ProgLangs)
+	if err != nil {
+		stateLock.Unlock()
+		return "", err
+	}
+	ticket.Options.ShowWelcome = true
+	ticket.Options.LanguageServer = inv.LanguageServer
+	session := cui.NewSession(ticket, inv.Candidate, inv.TimeLimit)
+	session.Invite = inv.Id
+	cuiSessions[ticket.Id] = session
+	stateLock.Unlock()
+	saveSession(session)
+	ticketsCreated.Inc()
+	notify(webhook.TICKET_CREATED, session)
+	log.Infoj(logging.Line("Invite started ticket", logging.TICKET_ID, ticket.Id, "invite_id", inv.Id, "candidate", inv.Candidate))
+	return ticket.Id, nil
+}
+
+// inviteMessages words the invite errors candidates see.
+var inviteMessages = map[error]cui.Message{
+	invite.ErrUsed:        cui.MSG_INVITE_USED,
+	invite.ErrExpired:     cui.MSG_INVITE_EXPIRED,
+	invite.ErrNotYetValid: cui.MSG_INVITE_EARLY,
+}
+
+// welcomeText is the text of the welcome page of inv in lang.
+func welcomeText(lang string, inv *invite.Invite) map[string]string {
+	text := map[string]string{
+		"Welcome": cui.T(lang, cui.MSG_WELCOME),
+		"Tasks":   cui.T(lang, cui.MSG_INVITE_TASKS, len(inv.Problems), inv.TimeLimit/60),
+		"Timer":   cui.T(lang, cui.MSG_INVITE_TIMER),
+		"Langs":   cui.T(lang, cui.MSG_INVITE_ANY_LANG),
+		"Buttons": cui.T(lang, cui.MSG_INVITE_BUTTONS),
+		"Once":    cui.T(lang, cui.MSG_INVITE_ONCE),
+		"Start":   cui.T(lang, cui.MSG_START),
+	}
+	if inv.Candidate != "" {
+		text["Welcome"] = cui.T(lang, cui.MSG_WELCOME_NAME, inv.Candidate)
+	}
+	if len(inv.ProgLangs) > 0 {
+		langs := []string{}
+		all := cui.DefaultProgLangList()
+		for _, name := range inv.ProgLangs {
+			langs = append(langs, all[name].Name)
+		}
+		text["Langs"] = cui.T(lang, cui.MSG_INVITE_LANGS, strings.Join(langs, ", "))
+	}
+	return text
+}
+
+func renderWelcome(c echo.Context, code int, inv *invite.Invite, err error) error {
+	lang := humanLang(c, nil)
+	data := map[string]interface{}{"Title": "Goonj2", "Invite": inv, "Sorry": cui.T(lang, cui.MSG_SORRY)}
+	if _, ok := err.(store.ErrNotFound); ok {
+		data["Error"] = cui.T(lang, cui.MSG_NO_INVITE)
+	} else if msg, ok := inviteMessages[err]; ok {
+		data["Error"] = cui.T(lang, msg)
+	} else if err != nil {
+		data["Error"] = err.Error()
+	}
+	if inv != nil {
+		data["CSRFToken"] = signer.CSRFToken(inv.Id)
+		data["Text"] = welcomeText(lang, inv)
+	}
+	return c.Render(code, "welcome.html", data)
+}
+
+func addInviteHandlers(e *echo.Echo) {
+	e.Get("/invite/:invite_id", func(c echo.Context) error {
+		inv, err := invites.Get(c.Param("invite_id"))
+		if err != nil {
+			return renderWelcome(c, http.StatusNotFound, nil, store.ErrNotFound{})
+		}
+		if ticketId, ok := signer.Cookie(c, sessionCookie); ok && ticketId == inv.TicketId {
+			return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/cui/%s", ticketId))
+		}
+		if err := inv.Check(time.Now()); err != nil {
+			return renderWelcome(c, http.StatusForbidden, inv, err)
+		}
+		return renderWelcome(c, http.StatusOK, inv, nil)
+	})
+
+	e.Post("/invite/:invite_id/start", func(c echo.Context) error {
+		inv, err := invites.Redeem(c.Param("invite_id"), startInvite)
+		if _, ok := err.(store.ErrNotFound); ok {
+			return renderWelcome(c, http.StatusNotFound, nil, err)
+		}
+		if err != nil {
+			return renderWelcome(c, http.StatusForbidden, inv, err)
+		}
+		if session, ok := getSession(inv.TicketId); ok {
+			bindSession(c, session)
+		}
+		return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/cui/%s", inv.TicketId))
+	}, signer.CSRF(func(c echo.Context) string { return c.Param("invite_id") }))
+}
+
+// inviteRequest is what recruiters set of an invite; the rest is the
+// server's.
+type inviteRequest struct {
+	Candidate      string    `json:"candidate"`
+	Problems       []string  `json:"problems"`
+	ProgLangs      []string  `json:"prg_langs"`
+	TimeLimit      int       `json:"time_limit"`
+	LanguageServer bool      `json:"language_server"`
+	ValidFrom      time.Time `json:"valid_from"`
+	ValidUntil     time.Time `json:"valid_until"`
+}
+
+func addInviteAdminHandlers(api *echo.Group) {
+	api.Post("/invites", func(c echo.Context) error {
+		req := &inviteRequest{}
+		if err := c.Bind(req); err != nil {
+			return err
+		}
+		inv := &invite.Invite{
+			Candidate:      req.Candidate,
+			Problems:       req.Problems,
+			ProgLangs:      req.ProgLangs,
+			TimeLimit:      req.TimeLimit,
+			LanguageServer: req.LanguageServer,
+			ValidFrom:      req.ValidFrom,
+			ValidUntil:     req.ValidUntil,
+		}
+		cli.Lock()
+		for _, id := range inv.Problems {
+			if _, ok := cli.ProbsList[id]; !ok {
+				cli.Unlock()
+				return echo.NewHTTPError(http.StatusBadRequest, cui.ErrUnknownProblem{id}.Error())
+			}
+		}
+		cli.Unlock()
+		all := cui.DefaultProgLangList()
+		for _, name := range inv.ProgLangs {
+			if _, ok := all[name]; !ok {
+				return echo.NewHTTPError(http.StatusBadRequest, cui.ErrUnknownProgLang{name}.Error())
+			}
+		}
+		inv.CreatedBy = auth.CurrentUser(c).Name
+		inv, err := invites.Create(inv)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		log.Infoj(requestLine(c, "Created invite", "user", inv.CreatedBy, "invite_id", inv.Id, "candidate", inv.Candidate))
+		return c.JSON(http.StatusCreated, viewInvite(inv))
+	}, auth.Require(auth.RECRUITER))
+
+	api.Get("/invites", func(c echo.Context) error {
+		list, err := invites.List()
+		if err != nil {
+			return err
+		}
+		views := []*inviteView{}
+		for _, inv := range list {
+			views = append(views, viewInvite(inv))
+		}
+		return c.JSON(http.StatusOK, views)
+	}, auth.Require(auth.RECRUITER, auth.REVIEWER))
+}
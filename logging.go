@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/labstack/echo"
+	"github.com/labstack/gommon/log"
+	"github.com/maddyonline/g2/cui"
+	"github.com/maddyonline/g2/logging"
+	"regexp"
+	"time"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID accepts the ids proxies in front of g2 assign.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestID tags each request with an id, the proxy's if it sent one, which
+// every log line about the request carries.
+func requestID(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := c.Request().Header().Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = cui.RandId(16)
+		}
+		c.Set(logging.REQUEST_ID, id)
+		c.Response().Header().Set(requestIDHeader, id)
+		return next(c)
+	}
+}
+
+// requestLine is a log line about the request c is handling, with the ids
+// of the request, ticket and task it is about.
+func requestLine(c echo.Context, msg string, kv ...interface{}) log.JSON {
+	j := logging.Line(msg, kv...)
+	if id, ok := c.Get(logging.REQUEST_ID).(string); ok {
+		j[logging.REQUEST_ID] = id
+	}
+	if ticket := requestTicket(c); ticket != "" {
+		j[logging.TICKET_ID] = ticket
+	}
+	if task := c.FormValue("task"); task != "" {
+		j[logging.TASK_ID] = task
+	}
+	return j
+}
+
+// accessLog logs every request once it is served. Only the path is logged;
+// queries may carry tokens.
+func accessLog(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+		if err != nil {
+			c.Error(err)
+		}
+		req, resp := c.Request(), c.Response()
+		log.Infoj(requestLine(c, "Request",
+			"method", req.Method(),
+			"path", req.URL().Path(),
+			"status", resp.Status(),
+			"latency_ms", time.Since(start).Seconds()*1000,
+			"bytes_out", resp.Size(),
+			"remote_ip", req.RealIP()))
+		return nil
+	}
+}
@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/labstack/gommon/log"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLine(t *testing.T) {
+	got := Line("Saved", TICKET_ID, "t1", TASK_ID, "", "bytes", 12)
+	want := log.JSON{"message": "Saved", TICKET_ID: "t1", "bytes": 12}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Line() = %v, want %v", got, want)
+	}
+
+	// The line comes out as a single JSON object with the fields.
+	buf := &bytes.Buffer{}
+	l := log.New("-")
+	l.SetOutput(buf)
+	l.Infoj(got)
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &fields); err != nil {
+		t.Fatalf("%q: %v", buf, err)
+	}
+	if fields["level"] != "INFO" || fields[TICKET_ID] != "t1" || fields["message"] != "Saved" {
+		t.Errorf("got %s", buf)
+	}
+}
+
+func TestSolution(t *testing.T) {
+	s := Solution("int main() { return 0; }")
+	if strings.Contains(s, "main") || !strings.Contains(s, "24 bytes") {
+		t.Errorf("Solution() = %q", s)
+	}
+	if Solution("a") == Solution("b") {
+		t.Error("different solutions look the same")
+	}
+	LogSolutions = true
+	defer func() { LogSolutions = false }()
+	if s := Solution("print(1)"); s != "print(1)" {
+		t.Errorf("Solution() = %q with LogSolutions", s)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if lvl, err := ParseLevel("WARN"); err != nil || lvl != log.WARN {
+		t.Errorf("ParseLevel(WARN) = %v, %v", lvl, err)
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("accepted verbose")
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/engine/standard"
+	"github.com/labstack/gommon/log"
+	"github.com/maddyonline/g2/cui"
+	"github.com/maddyonline/g2/guard"
+	"github.com/maddyonline/g2/logging"
+	"github.com/maddyonline/g2/lsp"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+var lspServers lsp.Servers
+
+// lspMaxServers bounds the language servers running at once, each in its
+// own container.
+var lspMaxServers int
+
+// lspBridges holds the open bridge of each ticket; opening another one, say
+// after switching languages, closes it. lspRunning counts the bridges being
+// opened or open.
+var (
+	lspLock    sync.Mutex
+	lspBridges = map[string]*websocket.Conn{}
+	lspRunning int
+)
+
+// closeLanguageServer hangs up the bridge of a ticket that closed.
+func closeLanguageServer(ticketId string) {
+	lspLock.Lock()
+	defer lspLock.Unlock()
+	if ws, ok := lspBridges[ticketId]; ok {
+		ws.Close()
+	}
+}
+
+// sameOrigin refuses WebSockets opened by pages of other sites, which the
+// browser would send the session cookie for.
+func sameOrigin(config *websocket.Config, req *http.Request) error {
+	origin, err := url.Parse(req.Header.Get("Origin"))
+	if err != nil || origin.Host != req.Host {
+		return websocket.ErrBadWebSocketOrigin
+	}
+	config.Origin = origin
+	return nil
+}
+
+func bridgeLanguageServer(session *cui.Session, command []string) http.Handler {
+	ticketId := session.Ticket.Id
+	return websocket.Server{Handshake: sameOrigin, Handler: func(ws *websocket.Conn) {
+		lspLock.Lock()
+		if old, ok := lspBridges[ticketId]; ok {
+			old.Close()
+		}
+		lspBridges[ticketId] = ws
+		lspLock.Unlock()
+		defer func() {
+			lspLock.Lock()
+			if lspBridges[ticketId] == ws {
+				delete(lspBridges, ticketId)
+			}
+			lspLock.Unlock()
+		}()
+		// The ticket may have closed while the socket was opened.
+		if session.Refresh().Closed() {
+			ws.Close()
+			return
+		}
+		if err := lsp.Bridge(ws, command, session.Deadline()); err != nil {
+			log.Warnj(logging.Line("Language server failed", logging.TICKET_ID, ticketId, "command", command[0], "error", err.Error()))
+		}
+	}}
+}
+
+// addLSPHandlers serves the language servers of tickets that have them
+// turned on as WebSockets: GET /lsp?ticket=<id>&prg_lang=<lang>&csrf_token=<token>.
+func addLSPHandlers(e *echo.Echo) {
+	e.Get("/lsp", func(c echo.Context) error {
+		ticketId := requestTicket(c)
+		// Browsers cannot set headers on WebSockets, so the token comes in
+		// the query.
+		if !signer.ValidCSRFToken(ticketId, c.QueryParam(guard.CSRFField)) {
+			return echo.NewHTTPError(http.StatusForbidden, cui.T(humanLang(c, nil), cui.MSG_INVALID_CSRF))
+		}
+		session, ok := getSession(ticketId)
+		if !ok {
+			return echo.NewHTTPError(http.StatusNotFound, cui.T(humanLang(c, nil), cui.MSG_NO_SESSION))
+		}
+		if !session.Ticket.Options.LanguageServer {
+			return echo.NewHTTPError(http.StatusNotFound, cui.T(humanLang(c, session), cui.MSG_NO_LSP))
+		}
+		if session.Refresh() != cui.STARTED {
+			return echo.NewHTTPError(http.StatusForbidden, cui.T(humanLang(c, session), cui.MSG_TICKET_CLOSED))
+		}
+		command, ok := lspServers[c.QueryParam("prg_lang")]
+		if !ok {
+			return echo.NewHTTPError(http.StatusNotFound, cui.T(humanLang(c, session), cui.MSG_NO_LSP_FOR, c.QueryParam("prg_lang")))
+		}
+		lspLock.Lock()
+		if running := lspRunning; running >= lspMaxServers {
+			lspLock.Unlock()
+			log.Warnj(requestLine(c, "Too many language servers", logging.TICKET_ID, ticketId, "running", running))
+			return echo.NewHTTPError(http.StatusServiceUnavailable, cui.T(humanLang(c, session), cui.MSG_LSP_BUSY))
+		}
+		lspRunning++
+		lspLock.Unlock()
+		defer func() {
+			lspLock.Lock()
+			lspRunning--
+			lspLock.Unlock()
+		}()
+		return standard.WrapHandler(bridgeLanguageServer(session, command))(c)
+	}, signer.RequireCookie(sessionCookie, requestTicket, rejectSession), trackSession)
+}
@@ -0,0 +1,54 @@
+package lsp
+
+import (
+	"golang.org/x/net/websocket"
+	"io"
+	"time"
+)
+
+// Bridge starts a server from command and relays messages between it and
+// ws until either side hangs up or deadline passes.
+func Bridge(ws *websocket.Conn, command []string, deadline time.Time) error {
+	server, err := Start(command)
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+	ws.MaxPayloadBytes = MAX_MESSAGE
+	ws.SetDeadline(deadline)
+
+	errs := make(chan error, 2)
+	go func() {
+		for {
+			var msg []byte
+			if err := websocket.Message.Receive(ws, &msg); err != nil {
+				errs <- err
+				return
+			}
+			if err := server.Send(msg); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	go func() {
+		for {
+			msg, err := server.Receive()
+			if err != nil {
+				errs <- err
+				return
+			}
+			// As text, which is what browsers expect JSON in.
+			if err := websocket.Message.Send(ws, string(msg)); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	err = <-errs
+	ws.Close()
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
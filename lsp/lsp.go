@@ -0,0 +1,189 @@
+// Package lsp runs language servers for the candidate's editor and bridges
+// them to WebSockets, one JSON-RPC message per WebSocket message.
+//
+// The servers speak the Language Server Protocol over stdio. By default
+// they run in a container of the IMAGE image without network access, which
+// has to provide clangd, gopls and pyright-langserver.
+package lsp
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const IMAGE = "g2/lsp"
+
+// MAX_MESSAGE bounds the size of a message in either direction.
+const MAX_MESSAGE = 1 << 20
+
+// Grace is how long a server has to exit once its input is closed before
+// it is killed.
+var Grace = 5 * time.Second
+
+type ErrMessage struct {
+	Problem string
+}
+
+func (e ErrMessage) Error() string {
+	return fmt.Sprintf("Bad language server message: %s", e.Problem)
+}
+
+// Servers maps CUI programming languages to the command running their
+// language server.
+type Servers map[string][]string
+
+func sandboxed(command ...string) []string {
+	return append([]string{
+		"docker", "run", "--rm", "-i", "--network=none",
+		"--memory=512m", "--cpus=1", "--pids-limit=128", "--read-only",
+		"--tmpfs=/tmp", "--tmpfs=/workspace", "-e", "HOME=/tmp", "-w", "/workspace",
+		IMAGE,
+	}, command...)
+}
+
+func DefaultServers() Servers {
+	return Servers{
+		"c":   sandboxed("clangd"),
+		"cpp": sandboxed("clangd"),
+		"go":  sandboxed("gopls"),
+		"py3": sandboxed("pyright-langserver", "--stdio"),
+	}
+}
+
+// LoadServers reads servers from a JSON file of the form
+// {"cpp": ["clangd"], ...}.
+func LoadServers(path string) (Servers, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	servers := Servers{}
+	if err := json.Unmarshal(b, &servers); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for lang, command := range servers {
+		if len(command) == 0 {
+			return nil, fmt.Errorf("%s: no command for %s", path, lang)
+		}
+	}
+	return servers, nil
+}
+
+// WriteMessage writes msg with the header of the base protocol.
+func WriteMessage(w io.Writer, msg []byte) error {
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(msg)); err != nil {
+		return err
+	}
+	_, err := w.Write(msg)
+	return err
+}
+
+// ReadMessage reads the content of the next message of the base protocol.
+func ReadMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return nil, ErrMessage{fmt.Sprintf("header %q", line)}
+		}
+		if strings.EqualFold(line[:i], "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(line[i+1:])); err != nil {
+				return nil, ErrMessage{fmt.Sprintf("header %q", line)}
+			}
+		}
+	}
+	if length < 0 {
+		return nil, ErrMessage{"no Content-Length"}
+	}
+	if length > MAX_MESSAGE {
+		return nil, ErrMessage{fmt.Sprintf("%d bytes", length)}
+	}
+	msg := make([]byte, length)
+	_, err := io.ReadFull(r, msg)
+	return msg, err
+}
+
+// Server is a running language server.
+type Server struct {
+	cmd       *exec.Cmd
+	container string
+	stdin     io.WriteCloser
+	stdout    *bufio.Reader
+	exited    chan struct{}
+}
+
+// named names the container of a "docker run" command, so that it can be
+// removed: killing the docker client leaves its container running. Other
+// commands are returned as they are, with no name.
+func named(command []string) ([]string, string) {
+	if len(command) < 2 || filepath.Base(command[0]) != "docker" || command[1] != "run" {
+		return command, ""
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	name := "g2-lsp-" + hex.EncodeToString(b)
+	return append([]string{command[0], "run", "--name", name}, command[2:]...), name
+}
+
+func Start(command []string) (*Server, error) {
+	command, container := named(command)
+	cmd := exec.Command(command[0], command[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	s := &Server{cmd, container, stdin, bufio.NewReader(stdout), make(chan struct{})}
+	go func() {
+		cmd.Wait()
+		close(s.exited)
+	}()
+	return s, nil
+}
+
+func (s *Server) Send(msg []byte) error {
+	return WriteMessage(s.stdin, msg)
+}
+
+func (s *Server) Receive() ([]byte, error) {
+	return ReadMessage(s.stdout)
+}
+
+// Close asks the server to exit by closing its input, and kills it, and
+// removes its container, if it is still running after Grace.
+func (s *Server) Close() {
+	s.stdin.Close()
+	select {
+	case <-s.exited:
+	case <-time.After(Grace):
+		s.cmd.Process.Kill()
+		<-s.exited
+		if s.container != "" {
+			exec.Command(s.cmd.Path, "rm", "-f", s.container).Run()
+		}
+	}
+}
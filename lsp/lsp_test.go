@@ -0,0 +1,113 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"golang.org/x/net/websocket"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessages(t *testing.T) {
+	buf := &bytes.Buffer{}
+	for _, msg := range []string{`{"jsonrpc":"2.0","id":1}`, `{}`} {
+		if err := WriteMessage(buf, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !strings.HasPrefix(buf.String(), "Content-Length: 24\r\n\r\n{") {
+		t.Errorf("got %q", buf.String())
+	}
+	r := bufio.NewReader(buf)
+	for _, want := range []string{`{"jsonrpc":"2.0","id":1}`, `{}`} {
+		msg, err := ReadMessage(r)
+		if err != nil || string(msg) != want {
+			t.Errorf("ReadMessage() = %q, %v; want %q", msg, err, want)
+		}
+	}
+
+	for _, bad := range []string{
+		"Content-Type: application/json\r\n\r\n{}",
+		"Content-Length: x\r\n\r\n",
+		"Content-Length: 2000000\r\n\r\n",
+		"garbage\r\n\r\n",
+	} {
+		if _, err := ReadMessage(bufio.NewReader(strings.NewReader(bad))); err == nil {
+			t.Errorf("ReadMessage(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestLoadServers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lsp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "servers.json")
+
+	ioutil.WriteFile(path, []byte(`{"cpp": ["clangd", "--log=error"]}`), 0644)
+	servers, err := LoadServers(path)
+	if err != nil || len(servers["cpp"]) != 2 {
+		t.Errorf("LoadServers() = %v, %v", servers, err)
+	}
+	ioutil.WriteFile(path, []byte(`{"cpp": []}`), 0644)
+	if _, err := LoadServers(path); err == nil {
+		t.Error("accepted a language without a command")
+	}
+}
+
+func TestBridge(t *testing.T) {
+	// cat echoes every message back, headers and all.
+	srv := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		Bridge(ws, []string{"cat"}, time.Now().Add(time.Minute))
+	}))
+	defer srv.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), "", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	for _, msg := range []string{`{"id":1,"method":"initialize"}`, `{"id":2}`} {
+		if err := websocket.Message.Send(ws, msg); err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		if err := websocket.Message.Receive(ws, &got); err != nil || got != msg {
+			t.Errorf("got %q, %v; want %q", got, err, msg)
+		}
+	}
+}
+
+func TestCloseKills(t *testing.T) {
+	Grace = 10 * time.Millisecond
+	defer func() { Grace = 5 * time.Second }()
+	server, err := Start([]string{"sleep", "10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	server.Close()
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Close took %s", d)
+	}
+}
+
+func TestNamed(t *testing.T) {
+	command, name := named(sandboxed("clangd"))
+	if !strings.HasPrefix(name, "g2-lsp-") || command[2] != "--name" || command[3] != name || command[len(command)-1] != "clangd" {
+		t.Errorf("named: %v, %q", command, name)
+	}
+	if _, other := named(sandboxed("clangd")); other == name {
+		t.Errorf("two containers named %q", name)
+	}
+	if command, name := named([]string{"clangd"}); name != "" || len(command) != 1 {
+		t.Errorf("named a plain command: %v, %q", command, name)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"github.com/labstack/echo"
+	"github.com/maddyonline/g2/auth"
+	"github.com/maddyonline/g2/cui"
+	"github.com/maddyonline/g2/metrics"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	registry = metrics.NewRegistry()
+
+	ticketsCreated = registry.Counter("g2_tickets_created_total",
+		"Tickets created, by the admin API, invites or practice.")
+	submissions = registry.Counter("g2_submissions_total",
+		"Solutions verified, judged or submitted, by mode, language and outcome.", "mode", "prg_lang", "outcome")
+	judgeQueue = registry.Gauge("g2_judge_queue_depth",
+		"Solutions waiting for their verdict.")
+	judgeLatency = registry.Histogram("g2_judge_duration_seconds",
+		"Time from a submission to its verdict, by mode.", metrics.DefBuckets, "mode")
+	executorFailures = registry.Counter("g2_executor_failures_total",
+		"Solutions the executor failed to compile or run, by language.", "prg_lang")
+	problemReloads = registry.Counter("g2_problem_reloads_total",
+		"Times the problems were republished after changing on disk.")
+)
+
+func init() {
+	registry.GaugeFunc("g2_active_sessions", "Tickets started and not yet closed.", func() float64 {
+		stateLock.Lock()
+		sessions := []*cui.Session{}
+		for _, session := range cuiSessions {
+			sessions = append(sessions, session)
+		}
+		stateLock.Unlock()
+		active := 0
+		for _, session := range sessions {
+			if session.Refresh() == cui.STARTED {
+				active++
+			}
+		}
+		return float64(active)
+	})
+	registry.GaugeFunc("g2_problem_errors", "Problems that failed to load.", func() float64 {
+		return float64(len(problemSet.Errors()))
+	})
+	registry.CounterFunc("g2_cache_hits_total", "Compile and run results answered from the cache.", func() float64 {
+		return float64(resultCache.Stats().Hits)
+	})
+	registry.CounterFunc("g2_cache_misses_total", "Compile and run results not in the cache.", func() float64 {
+		return float64(resultCache.Stats().Misses)
+	})
+	registry.CounterFunc("g2_cache_evictions_total", "Results evicted to make room in the cache.", func() float64 {
+		return float64(resultCache.Stats().Evictions)
+	})
+	registry.GaugeFunc("g2_cache_bytes", "Size of the results in the cache.", func() float64 {
+		return float64(resultCache.Stats().Bytes)
+	})
+}
+
+// knownProgLangs are the prg_lang label values; the language of a request
+// is the candidate's to choose, and every value would be a series.
+var knownProgLangs = cui.DefaultProgLangList()
+
+// progLangLabel is the prg_lang label for progLang: itself if g2 knows it,
+// "other" otherwise.
+func progLangLabel(progLang string) string {
+	if _, ok := knownProgLangs[progLang]; ok {
+		return progLang
+	}
+	return "other"
+}
+
+// outcome sums up a verdict for the submissions metric.
+func outcome(v *cui.VerifyStatus) string {
+	switch {
+	case v == nil:
+		return "unknown"
+	case v.Extra.Compile.OK == 0:
+		return "compile_error"
+	case v.Extra.Example.OK == 0:
+		return "failed"
+	}
+	return "passed"
+}
+
+// observeVerdict records a submission once its verdict is known.
+func observeVerdict(sub *cui.Submission) {
+	mode := strings.ToLower(sub.Mode)
+	judgeQueue.Add(-1)
+	judgeLatency.Observe(time.Since(sub.Time).Seconds(), mode)
+	submissions.Inc(mode, progLangLabel(sub.ProgLang), outcome(sub.Verdict))
+}
+
+// addMetricsHandlers serves /metrics to admins, such as Prometheus with an
+// admin's API token.
+func addMetricsHandlers(e *echo.Echo, a *auth.Auth) {
+	e.Get("/metrics", func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderContentType, metrics.CONTENT_TYPE)
+		c.Response().WriteHeader(http.StatusOK)
+		return registry.Write(c.Response())
+	}, a.Middleware(), auth.Require())
+}
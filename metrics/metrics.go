@@ -0,0 +1,255 @@
+// Package metrics keeps counters, gauges and histograms and writes them in
+// the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets suit latencies in seconds, from a cached answer to a slow judge.
+var DefBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// family is what every metric has: a name, help, a type and the names of
+// its labels. Its series are keyed by their label values.
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+	series map[string][]string
+	sync.Mutex
+}
+
+func newFamily(name, help, kind string, labels []string) family {
+	return family{name: name, help: help, kind: kind, labels: labels, series: map[string][]string{}}
+}
+
+// key returns the key of the series with values, adding it if new. The
+// caller holds the lock.
+func (f *family) key(values []string) string {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	k := strings.Join(values, "\xff")
+	if _, ok := f.series[k]; !ok {
+		f.series[k] = append([]string{}, values...)
+	}
+	return k
+}
+
+func (f *family) keys() []string {
+	keys := []string{}
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelString renders the labels of a series, plus extra pairs.
+func (f *family) labelString(k string, extra ...string) string {
+	pairs := []string{}
+	for i, v := range f.series[k] {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, f.labels[i], escaper.Replace(v)))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra[i], extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (f *family) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escaper.Replace(f.help), f.name, f.kind)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// value is a counter or a gauge.
+type value struct {
+	family
+	values map[string]float64
+}
+
+func (v *value) add(delta float64, labels []string) {
+	v.Lock()
+	defer v.Unlock()
+	v.values[v.key(labels)] += delta
+}
+
+func (v *value) write(w *bufio.Writer) {
+	v.Lock()
+	defer v.Unlock()
+	v.header(w)
+	for _, k := range v.keys() {
+		fmt.Fprintf(w, "%s%s %s\n", v.name, v.labelString(k), formatFloat(v.values[k]))
+	}
+}
+
+// Counter only goes up.
+type Counter struct {
+	value
+}
+
+func (c *Counter) Inc(labels ...string) {
+	c.add(1, labels)
+}
+
+func (c *Counter) Add(delta float64, labels ...string) {
+	if delta < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	c.add(delta, labels)
+}
+
+type Gauge struct {
+	value
+}
+
+func (g *Gauge) Set(v float64, labels ...string) {
+	g.Lock()
+	defer g.Unlock()
+	g.values[g.key(labels)] = v
+}
+
+func (g *Gauge) Add(delta float64, labels ...string) {
+	g.add(delta, labels)
+}
+
+// funcValue is a counter or gauge read when the metrics are written.
+type funcValue struct {
+	family
+	f func() float64
+}
+
+func (v *funcValue) write(w *bufio.Writer) {
+	v.header(w)
+	fmt.Fprintf(w, "%s %s\n", v.name, formatFloat(v.f()))
+}
+
+type histogramSeries struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+type Histogram struct {
+	family
+	buckets []float64
+	values  map[string]*histogramSeries
+}
+
+func (h *Histogram) Observe(v float64, labels ...string) {
+	h.Lock()
+	defer h.Unlock()
+	k := h.key(labels)
+	s, ok := h.values[k]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.values[k] = s
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.Lock()
+	defer h.Unlock()
+	h.header(w)
+	for _, k := range h.keys() {
+		s := h.values[k]
+		for i, upper := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelString(k, "le", formatFloat(upper)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelString(k, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelString(k), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelString(k), s.count)
+	}
+}
+
+// Registry holds metrics in the order they were added.
+type Registry struct {
+	metrics []metric
+	sync.Mutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) add(m metric) {
+	r.Lock()
+	defer r.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{value{newFamily(name, help, "counter", labels), map[string]float64{}}}
+	r.add(c)
+	return c
+}
+
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{value{newFamily(name, help, "gauge", labels), map[string]float64{}}}
+	r.add(g)
+	return g
+}
+
+// CounterFunc adds a counter kept elsewhere, read by f.
+func (r *Registry) CounterFunc(name, help string, f func() float64) {
+	r.add(&funcValue{newFamily(name, help, "counter", nil), f})
+}
+
+// GaugeFunc adds a gauge computed by f.
+func (r *Registry) GaugeFunc(name, help string, f func() float64) {
+	r.add(&funcValue{newFamily(name, help, "gauge", nil), f})
+}
+
+// Histogram adds a histogram with the given upper bounds, which must be
+// sorted.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{newFamily(name, help, "histogram", labels), buckets, map[string]*histogramSeries{}}
+	r.add(h)
+	return h
+}
+
+func (r *Registry) Write(w io.Writer) error {
+	r.Lock()
+	metrics := append([]metric{}, r.metrics...)
+	r.Unlock()
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	r := NewRegistry()
+	tickets := r.Counter("g2_tickets_created_total", "Tickets created.")
+	subs := r.Counter("g2_submissions_total", "Solutions submitted.", "mode", "outcome")
+	queue := r.Gauge("g2_judge_queue_depth", "Solutions being judged.")
+	r.GaugeFunc("g2_active_sessions", "Tickets being worked on.", func() float64 { return 2 })
+	latency := r.Histogram("g2_judge_duration_seconds", "Time to a verdict.", []float64{0.5, 1}, "mode")
+
+	tickets.Inc()
+	tickets.Add(2)
+	subs.Inc("verify", "passed")
+	subs.Inc("final", "failed")
+	subs.Inc("verify", "passed")
+	subs.Inc("verify", `say "hi"`)
+	queue.Add(3)
+	queue.Add(-1)
+	latency.Observe(0.2, "verify")
+	latency.Observe(0.7, "verify")
+	latency.Observe(3, "verify")
+
+	want := `# HELP g2_tickets_created_total Tickets created.
+# TYPE g2_tickets_created_total counter
+g2_tickets_created_total 3
+# HELP g2_submissions_total Solutions submitted.
+# TYPE g2_submissions_total counter
+g2_submissions_total{mode="final",outcome="failed"} 1
+g2_submissions_total{mode="verify",outcome="passed"} 2
+g2_submissions_total{mode="verify",outcome="say \"hi\""} 1
+# HELP g2_judge_queue_depth Solutions being judged.
+# TYPE g2_judge_queue_depth gauge
+g2_judge_queue_depth 2
+# HELP g2_active_sessions Tickets being worked on.
+# TYPE g2_active_sessions gauge
+g2_active_sessions 2
+# HELP g2_judge_duration_seconds Time to a verdict.
+# TYPE g2_judge_duration_seconds histogram
+g2_judge_duration_seconds_bucket{mode="verify",le="0.5"} 1
+g2_judge_duration_seconds_bucket{mode="verify",le="1"} 2
+g2_judge_duration_seconds_bucket{mode="verify",le="+Inf"} 3
+g2_judge_duration_seconds_sum{mode="verify"} 3.9
+g2_judge_duration_seconds_count{mode="verify"} 3
+`
+	buf := &bytes.Buffer{}
+	if err := r.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf, want)
+	}
+}
+
+func TestLabelCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("a missing label value was accepted")
+		}
+	}()
+	NewRegistry().Counter("c", "C.", "mode").Inc()
+}
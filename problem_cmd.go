@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	docker_client "github.com/docker/engine-api/client"
+	"github.com/maddyonline/g2/authoring"
+	"github.com/maddyonline/g2/problemset"
+	"github.com/maddyonline/umpire"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+const problemUsage = `usage: g2 problem new [-dir dir] [-title title] [-langs cpp,python] name
+       g2 problem validate [-dir dir] [name ...]
+       g2 problem check [-dir dir] [name ...]`
+
+// problemCommand is "g2 problem": it helps authors write problems without
+// starting the server. validate and check default to every problem in dir.
+func problemCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(problemUsage)
+	}
+	fs := flag.NewFlagSet("problem "+args[0], flag.ExitOnError)
+	dir := fs.String("dir", PROBLEMS_DIR, "problems directory")
+	switch args[0] {
+	case "new":
+		title := fs.String("title", "", "title of the problem (default its name)")
+		langs := fs.String("langs", "cpp,python", "languages to write templates and solutions for")
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			return errors.New(problemUsage)
+		}
+		name := fs.Arg(0)
+		if err := authoring.New(*dir, name, authoring.Options{*title, strings.Split(*langs, ",")}); err != nil {
+			return err
+		}
+		fmt.Printf("Created %s\n", filepath.Join(*dir, name))
+		return nil
+	case "validate":
+		fs.Parse(args[1:])
+		names, err := problemNames(*dir, fs.Args())
+		if err != nil {
+			return err
+		}
+		failed := 0
+		for _, name := range names {
+			errs := authoring.Validate(*dir, name, problemset.GetList)
+			if len(errs) == 0 {
+				fmt.Printf("ok   %s\n", name)
+				continue
+			}
+			failed++
+			fmt.Printf("FAIL %s\n", name)
+			for _, err := range errs {
+				fmt.Printf("     %v\n", err)
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d problems are invalid", failed, len(names))
+		}
+		return nil
+	case "check":
+		fs.Parse(args[1:])
+		names, err := problemNames(*dir, fs.Args())
+		if err != nil {
+			return err
+		}
+		abs, err := filepath.Abs(*dir)
+		if err != nil {
+			return err
+		}
+		dcli, err := docker_client.NewEnvClient()
+		if err != nil {
+			return err
+		}
+		agent := &umpire.Agent{dcli, abs}
+		failed := 0
+		for _, name := range names {
+			results, err := authoring.Check(agent, abs, name)
+			if err != nil {
+				return err
+			}
+			if len(results) == 0 {
+				failed++
+				fmt.Printf("FAIL %s: no solutions\n", name)
+			}
+			for _, r := range results {
+				rel, _ := filepath.Rel(abs, r.Path)
+				if r.OK() {
+					fmt.Printf("ok   %s\n", rel)
+					continue
+				}
+				failed++
+				if r.WantsPass {
+					fmt.Printf("FAIL %s: should pass but failed\n", rel)
+				} else {
+					fmt.Printf("FAIL %s: should fail but passed\n", rel)
+				}
+				fmt.Println(indent(r.Output, "     "))
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d checks failed", failed)
+		}
+		return nil
+	}
+	return errors.New(problemUsage)
+}
+
+// problemNames returns names, or every problem directory in dir if empty.
+func problemNames(dir string, names []string) ([]string, error) {
+	if len(names) > 0 {
+		return names, nil
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range infos {
+		if fi.IsDir() && !strings.HasPrefix(fi.Name(), ".") {
+			names = append(names, fi.Name())
+		}
+	}
+	return names, nil
+}
+
+func indent(s, prefix string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return prefix + strings.Join(lines, "\n"+prefix)
+}
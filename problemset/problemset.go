@@ -0,0 +1,467 @@
+// Package problemset keeps the problems of a directory loaded, reloading
+// the ones that change on disk.
+package problemset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"github.com/labstack/gommon/log"
+	"github.com/maddyonline/g2/logging"
+	"github.com/maddyonline/problems"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Settle is how long a problem must stay unchanged before it is reloaded,
+// so that an editor saving several files causes a single reload.
+var Settle = 200 * time.Millisecond
+
+// LoadFunc reads every problem found in dir.
+type LoadFunc func(dir string) (map[string]*problems.Problem, error)
+
+func GetList(dir string) (map[string]*problems.Problem, error) {
+	return problems.GetList(dir, ioutil.Discard)
+}
+
+// translationFile matches the translated statements that sit next to a
+// problem's statement, such as problem.cn.md.
+var translationFile = regexp.MustCompile(`^problem\.([a-z]+(?:-[a-zA-Z]+)?)\.md$`)
+
+type ErrNotAProblem struct {
+	Name string
+}
+
+func (e ErrNotAProblem) Error() string {
+	return fmt.Sprintf("%s is not a problem", e.Name)
+}
+
+// Set is the problems found in Dir, one sub-directory each. A problem that
+// fails to load keeps its last good version; the error is kept alongside.
+//
+// Every version of a problem is identified by a hash of its files and kept
+// in Snapshots, so tickets can go on being judged against the version they
+// started with.
+type Set struct {
+	Dir          string
+	Snapshots    string
+	load         LoadFunc
+	probs        map[string]*problems.Problem
+	versions     map[string]string
+	translations map[string]map[string]string
+	errs         map[string]error
+	// OnChange, if set, is called after Watch added, changed or removed a
+	// problem.
+	OnChange func()
+	*sync.Mutex
+	// reloading keeps Prune from removing a snapshot Reload is about to
+	// publish.
+	reloading sync.Mutex
+}
+
+func New(dir, snapshots string, load LoadFunc) *Set {
+	return &Set{
+		Dir:          dir,
+		Snapshots:    snapshots,
+		load:         load,
+		probs:        map[string]*problems.Problem{},
+		versions:     map[string]string{},
+		translations: map[string]map[string]string{},
+		errs:         map[string]error{},
+		Mutex:        &sync.Mutex{},
+	}
+}
+
+// names lists the problem directories.
+func (s *Set) names() ([]string, error) {
+	infos, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, fi := range infos {
+		if fi.IsDir() && !strings.HasPrefix(fi.Name(), ".") {
+			names = append(names, fi.Name())
+		}
+	}
+	return names, nil
+}
+
+// LoadAll loads every problem. Only failing to read Dir is an error;
+// problems that do not load are reported by Errors.
+func (s *Set) LoadAll() error {
+	names, err := s.names()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		s.Reload(name)
+	}
+	return nil
+}
+
+// VersionDir is the directory holding version of the problem name, in a
+// sub-directory called name as the loader and the judge expect.
+func (s *Set) VersionDir(name, version string) string {
+	return filepath.Join(s.Snapshots, name+"-"+version)
+}
+
+// hashDir hashes the names and contents of the files under dir.
+func hashDir(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fmt.Fprintf(h, "%s\x00%d\x00", filepath.ToSlash(rel), fi.Size())
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12], nil
+}
+
+// snapshot copies the problem into Snapshots and returns its version.
+// Copying first means the version matches what gets loaded even if the
+// files change meanwhile.
+func (s *Set) snapshot(name string) (string, error) {
+	if err := os.MkdirAll(s.Snapshots, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempDir(s.Snapshots, "."+name)
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	if err := CopyDir(filepath.Join(s.Dir, name), filepath.Join(tmp, name)); err != nil {
+		return "", err
+	}
+	version, err := hashDir(filepath.Join(tmp, name))
+	if err != nil {
+		return "", err
+	}
+	dir := s.VersionDir(name, version)
+	if _, err := os.Stat(dir); err == nil {
+		return version, nil
+	}
+	return version, os.Rename(tmp, dir)
+}
+
+// loadOne snapshots a problem and loads the snapshot, which holds just
+// that problem.
+func (s *Set) loadOne(name string) (*problems.Problem, string, map[string]string, error) {
+	version, err := s.snapshot(name)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	list, err := s.load(s.VersionDir(name, version))
+	if err != nil {
+		return nil, "", nil, err
+	}
+	p, ok := list[name]
+	if !ok {
+		return nil, "", nil, ErrNotAProblem{name}
+	}
+	translations, err := loadTranslations(filepath.Join(s.VersionDir(name, version), name))
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return p, version, translations, nil
+}
+
+// loadTranslations reads the translated statements of the problem in dir,
+// by language.
+func loadTranslations(dir string) (map[string]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	translations := map[string]string{}
+	for _, fi := range infos {
+		m := translationFile.FindStringSubmatch(fi.Name())
+		if m == nil || fi.IsDir() {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		translations[m[1]] = string(b)
+	}
+	return translations, nil
+}
+
+// Reload reads the problem again, or forgets it if its directory is gone.
+// It reports whether the problems changed.
+func (s *Set) Reload(name string) bool {
+	s.reloading.Lock()
+	defer s.reloading.Unlock()
+	fi, err := os.Stat(filepath.Join(s.Dir, name))
+	if err != nil || !fi.IsDir() {
+		s.Lock()
+		defer s.Unlock()
+		_, ok := s.probs[name]
+		delete(s.probs, name)
+		delete(s.versions, name)
+		delete(s.translations, name)
+		delete(s.errs, name)
+		if ok {
+			log.Infoj(logging.Line("Removed problem", "problem", name))
+		}
+		return ok
+	}
+	p, version, translations, err := s.loadOne(name)
+	s.Lock()
+	defer s.Unlock()
+	if err != nil {
+		s.errs[name] = err
+		log.Warnj(logging.Line("Problem failed to load", "problem", name, "error", err.Error()))
+		return false
+	}
+	delete(s.errs, name)
+	if s.versions[name] == version {
+		return false
+	}
+	s.probs[name] = p
+	s.versions[name] = version
+	s.translations[name] = translations
+	log.Infoj(logging.Line("Loaded problem", "problem", name, "version", version))
+	return true
+}
+
+// Prune removes the snapshots of versions that are neither current nor
+// inUse, and returns the directories it removed.
+func (s *Set) Prune(inUse func(name, version string) bool) ([]string, error) {
+	s.reloading.Lock()
+	defer s.reloading.Unlock()
+	infos, err := ioutil.ReadDir(s.Snapshots)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	current := s.Versions()
+	removed := []string{}
+	for _, fi := range infos {
+		i := strings.LastIndex(fi.Name(), "-")
+		if !fi.IsDir() || strings.HasPrefix(fi.Name(), ".") || i < 0 {
+			continue
+		}
+		name, version := fi.Name()[:i], fi.Name()[i+1:]
+		if current[name] == version || inUse(name, version) {
+			continue
+		}
+		dir := s.VersionDir(name, version)
+		if err := os.RemoveAll(dir); err != nil {
+			return removed, err
+		}
+		removed = append(removed, dir)
+	}
+	return removed, nil
+}
+
+// Problems returns the problems currently loaded. The map is a copy.
+func (s *Set) Problems() map[string]*problems.Problem {
+	s.Lock()
+	defer s.Unlock()
+	probs := map[string]*problems.Problem{}
+	for name, p := range s.probs {
+		probs[name] = p
+	}
+	return probs
+}
+
+// Versions returns the current version of every loaded problem.
+func (s *Set) Versions() map[string]string {
+	s.Lock()
+	defer s.Unlock()
+	versions := map[string]string{}
+	for name, v := range s.versions {
+		versions[name] = v
+	}
+	return versions
+}
+
+// Translations returns the translated statements of every loaded problem,
+// by problem and language. The statements of the problems themselves are
+// in English.
+func (s *Set) Translations() map[string]map[string]string {
+	s.Lock()
+	defer s.Unlock()
+	translations := map[string]map[string]string{}
+	for name, t := range s.translations {
+		translations[name] = t
+	}
+	return translations
+}
+
+// Errors returns why problems failed to load, by name.
+func (s *Set) Errors() map[string]error {
+	s.Lock()
+	defer s.Unlock()
+	errs := map[string]error{}
+	for name, err := range s.errs {
+		errs[name] = err
+	}
+	return errs
+}
+
+// Names lists loaded and broken problems alike.
+func (s *Set) Names() []string {
+	s.Lock()
+	defer s.Unlock()
+	names := []string{}
+	for name := range s.probs {
+		names = append(names, name)
+	}
+	for name := range s.errs {
+		if _, ok := s.probs[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// problemName is the problem a changed path belongs to, if any.
+func (s *Set) problemName(path string) string {
+	rel, err := filepath.Rel(s.Dir, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	name := strings.SplitN(rel, string(filepath.Separator), 2)[0]
+	if strings.HasPrefix(name, ".") {
+		return ""
+	}
+	return name
+}
+
+// watchTree watches dir and its sub-directories; fsnotify is not recursive.
+func watchTree(w *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if strings.HasPrefix(fi.Name(), ".") && path != dir {
+				return filepath.SkipDir
+			}
+			return w.Add(path)
+		}
+		return nil
+	})
+}
+
+// Watch reloads problems as they change on disk until quit is closed.
+func (s *Set) Watch(quit <-chan struct{}) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watchTree(w, s.Dir); err != nil {
+		w.Close()
+		return err
+	}
+	go func() {
+		defer w.Close()
+		pending := map[string]*time.Timer{}
+		reload := make(chan string)
+		for {
+			select {
+			case ev := <-w.Events:
+				if ev.Op&fsnotify.Create != 0 {
+					if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+						if err := watchTree(w, ev.Name); err != nil {
+							log.Errorj(logging.Line("Watching problems failed", "path", ev.Name, "error", err.Error()))
+						}
+					}
+				}
+				name := s.problemName(ev.Name)
+				if name == "" {
+					continue
+				}
+				if t, ok := pending[name]; ok {
+					t.Reset(Settle)
+					continue
+				}
+				pending[name] = time.AfterFunc(Settle, func() {
+					select {
+					case reload <- name:
+					case <-quit:
+					}
+				})
+			case name := <-reload:
+				delete(pending, name)
+				if s.Reload(name) && s.OnChange != nil {
+					s.OnChange()
+				}
+			case err := <-w.Errors:
+				log.Errorj(logging.Line("Watching problems failed", "error", err.Error()))
+			case <-quit:
+				for _, t := range pending {
+					t.Stop()
+				}
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// CopyDir copies the files of src into dst, which is created.
+func CopyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if fi.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target, fi.Mode())
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,216 @@
+package problemset
+
+import (
+	"errors"
+	"github.com/maddyonline/problems"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeLoad stands in for problems.GetList: a problem is a directory with a
+// title.txt, and a title of "BAD" fails to parse.
+func fakeLoad(dir string) (map[string]*problems.Problem, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	list := map[string]*problems.Problem{}
+	for _, fi := range infos {
+		b, err := ioutil.ReadFile(filepath.Join(dir, fi.Name(), "title.txt"))
+		if err != nil {
+			continue
+		}
+		title := strings.TrimSpace(string(b))
+		if title == "BAD" {
+			return nil, errors.New("bad title")
+		}
+		list[fi.Name()] = &problems.Problem{Name: fi.Name(), Title: title}
+	}
+	return list, nil
+}
+
+func writeProblem(t *testing.T, dir, name, title string) {
+	if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name, "title.txt"), []byte(title), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func title(s *Set, name string) string {
+	p, ok := s.Problems()[name]
+	if !ok {
+		return ""
+	}
+	return p.Title
+}
+
+func TestLoadAll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "g2-problemset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	probs := filepath.Join(dir, "problems")
+	writeProblem(t, probs, "good", "Good")
+	writeProblem(t, probs, "bad", "BAD")
+	os.MkdirAll(filepath.Join(probs, "empty"), 0755)
+
+	s := New(probs, filepath.Join(dir, "versions"), fakeLoad)
+	if err := s.LoadAll(); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Problems()) != 1 || title(s, "good") != "Good" {
+		t.Errorf("problems: %v", s.Problems())
+	}
+	errs := s.Errors()
+	if len(errs) != 2 || errs["bad"] == nil || errs["empty"] == nil {
+		t.Errorf("errors: %v", errs)
+	}
+
+	// A broken edit keeps the previous version.
+	writeProblem(t, probs, "good", "BAD")
+	if s.Reload("good") || title(s, "good") != "Good" || s.Errors()["good"] == nil {
+		t.Errorf("after a broken edit: %q, %v", title(s, "good"), s.Errors())
+	}
+	os.RemoveAll(filepath.Join(probs, "good"))
+	if !s.Reload("good") || len(s.Problems()) != 0 {
+		t.Errorf("after removal: %v", s.Problems())
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "g2-problemset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	probs := filepath.Join(dir, "problems")
+	writeProblem(t, probs, "one", "One")
+
+	Settle = 10 * time.Millisecond
+	s := New(probs, filepath.Join(dir, "versions"), fakeLoad)
+	s.LoadAll()
+	changed := make(chan bool, 10)
+	s.OnChange = func() { changed <- true }
+	quit := make(chan struct{})
+	defer close(quit)
+	if err := s.Watch(quit); err != nil {
+		t.Fatal(err)
+	}
+
+	wait := func(what string) {
+		select {
+		case <-changed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no reload after %s", what)
+		}
+	}
+	writeProblem(t, probs, "two", "Two")
+	wait("adding a problem")
+	if title(s, "two") != "Two" {
+		t.Errorf("two: %q", title(s, "two"))
+	}
+	writeProblem(t, probs, "one", "One, edited")
+	wait("editing a problem")
+	if title(s, "one") != "One, edited" {
+		t.Errorf("one: %q", title(s, "one"))
+	}
+}
+
+func TestVersions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "g2-problemset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	probs := filepath.Join(dir, "problems")
+	writeProblem(t, probs, "one", "One")
+	s := New(probs, filepath.Join(dir, "versions"), fakeLoad)
+	s.LoadAll()
+	v1 := s.Versions()["one"]
+	if v1 == "" {
+		t.Fatal("no version")
+	}
+	if s.Reload("one") {
+		t.Error("reloading unchanged files made a new version")
+	}
+
+	writeProblem(t, probs, "one", "One, edited")
+	if !s.Reload("one") {
+		t.Fatal("editing did not make a new version")
+	}
+	v2 := s.Versions()["one"]
+	if v2 == v1 {
+		t.Errorf("both versions are %s", v1)
+	}
+	// The old version is still there for the tickets that use it.
+	b, err := ioutil.ReadFile(filepath.Join(s.VersionDir("one", v1), "one", "title.txt"))
+	if err != nil || string(b) != "One" {
+		t.Errorf("old version: %q, %v", b, err)
+	}
+
+	writeProblem(t, probs, "one", "One")
+	s.Reload("one")
+	if s.Versions()["one"] != v1 {
+		t.Errorf("restoring the files gave version %s, want %s", s.Versions()["one"], v1)
+	}
+}
+
+func TestTranslations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "g2-problemset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	probs := filepath.Join(dir, "problems")
+	writeProblem(t, probs, "one", "One")
+	ioutil.WriteFile(filepath.Join(probs, "one", "problem.cn.md"), []byte("一"), 0644)
+	ioutil.WriteFile(filepath.Join(probs, "one", "problem.md"), []byte("One"), 0644)
+	s := New(probs, filepath.Join(dir, "versions"), fakeLoad)
+	s.LoadAll()
+	if tr := s.Translations()["one"]; len(tr) != 1 || tr["cn"] != "一" {
+		t.Errorf("got %v", tr)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "g2-problemset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	probs := filepath.Join(dir, "problems")
+	writeProblem(t, probs, "one", "One")
+	writeProblem(t, probs, "two", "Two")
+	s := New(probs, filepath.Join(dir, "versions"), fakeLoad)
+	s.LoadAll()
+	v1 := s.Versions()["one"]
+	writeProblem(t, probs, "one", "One, edited")
+	s.Reload("one")
+	v2 := s.Versions()["one"]
+	writeProblem(t, probs, "one", "One, edited again")
+	s.Reload("one")
+	v3 := s.Versions()["one"]
+
+	// A ticket still uses the first version; nothing uses the second.
+	removed, err := s.Prune(func(name, version string) bool {
+		return name == "one" && version == v1
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0] != s.VersionDir("one", v2) {
+		t.Errorf("removed %v, want the version %s", removed, v2)
+	}
+	for _, dir := range []string{s.VersionDir("one", v1), s.VersionDir("one", v3), s.VersionDir("two", s.Versions()["two"])} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("%s: %v", dir, err)
+		}
+	}
+}
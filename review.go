@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/labstack/echo"
+	"github.com/maddyonline/g2/auth"
+	"github.com/maddyonline/g2/cui"
+	"github.com/maddyonline/g2/frontend"
+	"html/template"
+	"net/http"
+)
+
+// closedReviews returns the reviews of every finished or timed out ticket.
+func closedReviews() []*frontend.Review {
+	stateLock.Lock()
+	sessions := []*cui.Session{}
+	for _, session := range cuiSessions {
+		sessions = append(sessions, session)
+	}
+	stateLock.Unlock()
+	reviews := []*frontend.Review{}
+	for _, session := range sessions {
+		switch session.Refresh() {
+		case cui.FINISHED, cui.TIMEDOUT:
+			reviews = append(reviews, frontend.NewReview(session, sessionTasks(session)))
+		}
+	}
+	return reviews
+}
+
+// addReviewHandlers serves the reviewer dashboard. Browsers log in with
+// HTTP basic auth using their local account.
+func addReviewHandlers(e *echo.Echo, a *auth.Auth, tmpl *template.Template) {
+	rv := e.Group("/review", a.Middleware(), auth.Require(auth.REVIEWER, auth.RECRUITER))
+
+	rv.Get("", func(c echo.Context) error {
+		b, err := frontend.ReviewList(tmpl, closedReviews())
+		if err != nil {
+			return err
+		}
+		return c.HTML(http.StatusOK, string(b))
+	})
+
+	rv.Get("/:ticket_id", func(c echo.Context) error {
+		session, ok := getSession(c.Param("ticket_id"))
+		if !ok {
+			return echo.NewHTTPError(http.StatusNotFound, "No such ticket")
+		}
+		b, err := frontend.ReviewTicket(tmpl, frontend.NewReview(session, sessionTasks(session)))
+		if err != nil {
+			return err
+		}
+		return c.HTML(http.StatusOK, string(b))
+	})
+}
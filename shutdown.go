@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/engine/standard"
+	"github.com/labstack/gommon/log"
+	"github.com/maddyonline/g2/cui"
+	"github.com/maddyonline/g2/logging"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// SHUTDOWN_GRACE is how long requests still being served get once judging
+// is over; the candidates' pages poll for verdicts until then.
+const SHUTDOWN_GRACE = 10 * time.Second
+
+// Submissions hold shutdownLock for reading until their judging has started,
+// so none starts once shuttingDown is set.
+var (
+	shutdownLock sync.RWMutex
+	shuttingDown bool
+)
+
+// acceptSubmissions refuses solutions sent once the server is shutting down.
+func acceptSubmissions(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		shutdownLock.RLock()
+		defer shutdownLock.RUnlock()
+		if shuttingDown {
+			session, _ := getSession(c.FormValue("ticket"))
+			c.Response().Header().Set("Retry-After", "60")
+			return echo.NewHTTPError(http.StatusServiceUnavailable, cui.T(humanLang(c, session), cui.MSG_RESTARTING))
+		}
+		return next(c)
+	}
+}
+
+// stopOnSignal shuts the server down on SIGTERM or SIGINT. The returned
+// channel is closed once it is safe to exit.
+func stopOnSignal(server *standard.Server, timeout time.Duration, quit chan struct{}) <-chan struct{} {
+	stopped := make(chan struct{})
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		sig := <-sigs
+		log.Infoj(logging.Line("Shutting down", "signal", sig.String(), "timeout_sec", timeout.Seconds()))
+		shutdown(server, timeout)
+		close(quit)
+		close(stopped)
+	}()
+	return stopped
+}
+
+// shutdown stops taking submissions, waits up to timeout for the solutions
+// being judged and the webhook requests under way, then stops server and
+// saves every ticket.
+func shutdown(server *standard.Server, timeout time.Duration) {
+	shutdownLock.Lock()
+	shuttingDown = true
+	shutdownLock.Unlock()
+
+	// Webhooks waiting to be retried are saved and resumed on the next
+	// start rather than waited for.
+	hooks.Stop()
+	drained := make(chan struct{})
+	go func() {
+		cli.Wait()
+		// Final verdicts fire webhooks, so their first attempts come after
+		// them.
+		hooks.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		log.Infoj(logging.Line("Drained judgments"))
+	case <-time.After(timeout):
+		log.Warnj(logging.Line("Gave up waiting for judgments", "timeout_sec", timeout.Seconds()))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_GRACE)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Warnj(logging.Line("Closing connections failed", "error", err.Error()))
+	}
+	saveSessions()
+}
+
+// saveSessions writes every ticket to the store.
+func saveSessions() {
+	stateLock.Lock()
+	sessions := []*cui.Session{}
+	for _, session := range cuiSessions {
+		sessions = append(sessions, session)
+	}
+	stateLock.Unlock()
+	for _, session := range sessions {
+		saveSession(session)
+	}
+	log.Infoj(logging.Line("Saved tickets", "tickets", len(sessions)))
+}
@@ -468,8 +468,16 @@ function CandidateUi(options)
                 Console.msg_ok(_compile_msg);
             }
             else {
-                Console.msg_error(_compile_msg);
+                Console.msg_error('<pre>' + $('<span>').text(_compile_msg).html() + '</pre>');
             }
+            self.editor.setDiagnostics($.map($(xml).find('compile > diagnostic'), function(d) {
+                return {
+                    line: parseInt($(d).find('line').text(), 10),
+                    column: parseInt($(d).find('column').text(), 10),
+                    severity: $(d).find('severity').text(),
+                    message: $(d).find('message').text()
+                };
+            }));
 
             if (_compile=='1') {
                 var _example = xmlNodeValue(xml,'example > ok');
@@ -1051,7 +1059,12 @@ function CandidateUi(options)
     };
 
     self.exit = function(url) {
-        window.location.href = url;
+        // Closing the ticket is a POST carrying the token of this page, so
+        // that other sites cannot end the test with a link.
+        var token = self.options.csrf_token || $('#page input[name=csrf_token]').val();
+        $('<form method="post"></form>').attr('action', url)
+            .append($('<input type="hidden" name="csrf_token">').val(token))
+            .appendTo('body').submit();
     };
 
     self.quitAction = function() {
@@ -1080,6 +1093,17 @@ function CandidateUi(options)
     self.setupEditor = function() {
         self.editor = AceEditor();
         self.editor.onChangeEvent(self.updateModified);
+        if (self.options.language_server && self.editor.useLanguageServer)
+            self.editor.useLanguageServer(self.languageServerUrl);
+    };
+
+    self.languageServerUrl = function(prg_lang) {
+        var scheme = window.location.protocol == 'https:' ? 'wss://' : 'ws://';
+        return scheme + window.location.host + (self.options.urls.lsp || '/lsp/') + '?' + $.param({
+            ticket: self.options.ticket_id,
+            prg_lang: prg_lang,
+            csrf_token: self.options.csrf_token
+        });
     };
 
     self.setupModals = function() {
@@ -1371,7 +1395,16 @@ function CandidateUi(options)
         }, 500);
     };
 
+    self.setupCsrf = function() {
+        // The server rejects CUI POSTs that don't carry the token of this page.
+        var token = self.options.csrf_token || $('#page input[name=csrf_token]').val();
+        if (!token)
+            return;
+        $.ajaxSetup({headers: {'X-CSRF-Token': token}});
+    };
+
     self.init = function() {
+        self.setupCsrf();
         self.setupEditor();
         self.setupModals();
         self.setupButtons();
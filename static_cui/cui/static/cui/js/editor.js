@@ -79,6 +79,7 @@ function Editor() {
     self.setNoNewLines = function() {};
     self.setReadOnlyRegions = function() {};
     self.enforceReadOnlyRegions = function() {};
+    self.setDiagnostics = function(diagnostics) {};
 
     return self;
 }
@@ -171,12 +172,39 @@ function AceEditor() {
             self.ace.getSession().setMode({path: mode, inline: true});
         else
             self.ace.getSession().setMode({path: mode});
+        if (self.lsp)
+            self.lsp.connect(prg_lang);
+    };
+
+    /* Completions and diagnostics from a language server; url(prg_lang)
+       is the WebSocket to reach it at. */
+    self.useLanguageServer = function(url) {
+        self.lsp = LanguageServer(self, url);
+        ace.require('ace/ext/language_tools').addCompleter({
+            getCompletions: function(editor, session, pos, prefix, callback) {
+                self.lsp.complete(pos, callback);
+            }
+        });
+        self.ace.setOptions({ enableLiveAutocompletion: true });
+        self.ace.on('change', self.lsp.change);
     };
 
     self.setEditable = function(editable) {
         self.ace.setReadOnly(!editable);
     };
 
+    /* Compiler messages, with lines and columns counted from 1. */
+    self.setDiagnostics = function(diagnostics) {
+        self.ace.getSession().setAnnotations($.map(diagnostics, function(d) {
+            return {
+                row: d.line - 1,
+                column: Math.max(d.column - 1, 0),
+                text: d.message,
+                type: d.severity
+            };
+        }));
+    };
+
     self.clearHistory = function() {
         self.ace.getSession().setUndoManager(new ace.UndoManager());
     };
@@ -302,3 +330,177 @@ function AceEditor() {
 
     return self;
 }
+
+
+/* LanguageServer speaks the Language Server Protocol with the server g2
+   runs for the ticket, one JSON-RPC message per WebSocket message. The
+   solution is kept in a single document the server sees in /workspace. */
+function LanguageServer(editor, url) {
+    var self = {
+        ws: null,
+        ready: false,
+        uri: null,
+        version: 0,
+        next_id: 1,
+        pending: {},
+        change_hnd: null
+    };
+
+    var DOCUMENTS = {
+        'c': {ext: 'c', language_id: 'c'},
+        'cpp': {ext: 'cpp', language_id: 'cpp'},
+        'go': {ext: 'go', language_id: 'go'},
+        'py3': {ext: 'py', language_id: 'python'}
+    };
+    var SEVERITIES = {1: 'error', 2: 'warning'};
+
+    self.connect = function(prg_lang) {
+        self.close();
+        var doc = DOCUMENTS[prg_lang];
+        if (!doc)
+            return;
+        self.uri = 'file:///workspace/solution.' + doc.ext;
+        self.language_id = doc.language_id;
+        var ws = new WebSocket(url(prg_lang));
+        self.ws = ws;
+        ws.onopen = function() {
+            self.request('initialize', {
+                processId: null,
+                rootUri: 'file:///workspace',
+                capabilities: {
+                    textDocument: {
+                        completion: {completionItem: {snippetSupport: false}},
+                        publishDiagnostics: {}
+                    }
+                }
+            }, function() {
+                self.notify('initialized', {});
+                self.ready = true;
+                self.open();
+            });
+        };
+        ws.onmessage = function(e) {
+            self.receive(JSON.parse(e.data));
+        };
+        ws.onclose = function() {
+            if (self.ws !== ws)
+                return;
+            Log.info("Language server", "connection closed");
+            self.ws = null;
+            self.ready = false;
+            editor.ace.getSession().clearAnnotations();
+        };
+    };
+
+    self.close = function() {
+        if (self.ws) {
+            var ws = self.ws;
+            self.ws = null;
+            ws.close();
+        }
+        self.ready = false;
+        self.pending = {};
+        editor.ace.getSession().clearAnnotations();
+    };
+
+    self.send = function(msg) {
+        msg.jsonrpc = '2.0';
+        self.ws.send(JSON.stringify(msg));
+    };
+
+    self.request = function(method, params, callback) {
+        var id = self.next_id++;
+        self.pending[id] = callback;
+        self.send({id: id, method: method, params: params});
+    };
+
+    self.notify = function(method, params) {
+        self.send({method: method, params: params});
+    };
+
+    self.receive = function(msg) {
+        if (msg.method === undefined) {
+            var callback = self.pending[msg.id];
+            delete self.pending[msg.id];
+            if (callback)
+                callback(msg.result, msg.error);
+        }
+        else if (msg.method == 'textDocument/publishDiagnostics') {
+            if (msg.params.uri == self.uri)
+                self.showDiagnostics(msg.params.diagnostics);
+        }
+        else if (msg.id !== undefined) {
+            // Requests from the server, such as workspace/configuration,
+            // get the defaults.
+            var result = null;
+            if (msg.method == 'workspace/configuration')
+                result = $.map(msg.params.items, function() { return [null]; });
+            self.send({id: msg.id, result: result});
+        }
+    };
+
+    self.open = function() {
+        self.version = 1;
+        self.notify('textDocument/didOpen', {
+            textDocument: {
+                uri: self.uri,
+                languageId: self.language_id,
+                version: self.version,
+                text: editor.ace.getValue()
+            }
+        });
+    };
+
+    self.sync = function() {
+        clearTimeout(self.change_hnd);
+        self.change_hnd = null;
+        if (!self.ready)
+            return;
+        self.version++;
+        self.notify('textDocument/didChange', {
+            textDocument: {uri: self.uri, version: self.version},
+            contentChanges: [{text: editor.ace.getValue()}]
+        });
+    };
+
+    self.change = function() {
+        if (!self.ready)
+            return;
+        clearTimeout(self.change_hnd);
+        self.change_hnd = setTimeout(self.sync, 300);
+    };
+
+    self.complete = function(pos, callback) {
+        if (!self.ready)
+            return callback(null, []);
+        if (self.change_hnd)
+            self.sync();
+        self.request('textDocument/completion', {
+            textDocument: {uri: self.uri},
+            position: {line: pos.row, character: pos.column}
+        }, function(result) {
+            var items = (result && result.items) || result || [];
+            callback(null, $.map(items, function(item) {
+                return {
+                    caption: item.label,
+                    value: item.insertText || item.label,
+                    meta: item.detail || 'lsp',
+                    score: 1000
+                };
+            }));
+        });
+    };
+
+    self.showDiagnostics = function(diagnostics) {
+        editor.ace.getSession().setAnnotations($.map(diagnostics, function(d) {
+            return {
+                row: d.range.start.line,
+                column: d.range.start.character,
+                text: d.message,
+                type: SEVERITIES[d.severity] || 'info'
+            };
+        }));
+    };
+
+    return self;
+}
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const ext = ".json"
+
+type ErrNotFound struct {
+	Kind string
+	Id   string
+}
+
+func (e ErrNotFound) Error() string {
+	return fmt.Sprintf("%s %q not found", e.Kind, e.Id)
+}
+
+// Store keeps JSON documents on disk, one file per document, grouped in a
+// directory per kind (tickets, users, ...).
+type Store struct {
+	Dir string
+	*sync.Mutex
+}
+
+func Open(dir string) (*Store, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Store{Dir: dir, Mutex: &sync.Mutex{}}, nil
+}
+
+func (s *Store) path(kind, id string) (string, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("store: invalid %s id %q", kind, id)
+	}
+	return filepath.Join(s.Dir, kind, id+ext), nil
+}
+
+// Put writes v under kind/id. The file is replaced atomically so readers
+// never observe a partially written document.
+func (s *Store) Put(kind, id string, v interface{}) error {
+	p, err := s.path(kind, id)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(p), "."+id)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *Store) Get(kind, id string, v interface{}) error {
+	p, err := s.path(kind, id)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	b, err := ioutil.ReadFile(p)
+	s.Unlock()
+	if os.IsNotExist(err) {
+		return ErrNotFound{kind, id}
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func (s *Store) Delete(kind, id string) error {
+	p, err := s.path(kind, id)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	err = os.Remove(p)
+	if os.IsNotExist(err) {
+		return ErrNotFound{kind, id}
+	}
+	return err
+}
+
+// List returns the sorted ids of all documents of the given kind.
+func (s *Store) List(kind string) ([]string, error) {
+	s.Lock()
+	infos, err := ioutil.ReadDir(filepath.Join(s.Dir, kind))
+	s.Unlock()
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, ext))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+type doc struct {
+	Name  string
+	Count int
+}
+
+func TestPutGetList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "g2-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Put("tickets", "b", &doc{"second", 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("tickets", "a", &doc{"first", 1}); err != nil {
+		t.Fatal(err)
+	}
+	got := &doc{}
+	if err := s.Get("tickets", "a", got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, &doc{"first", 1}) {
+		t.Errorf("Get: got %+v", got)
+	}
+	ids, err := s.List("tickets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []string{"a", "b"}) {
+		t.Errorf("List: got %v", ids)
+	}
+
+	if err := s.Delete("tickets", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Get("tickets", "a", got).(ErrNotFound); !ok {
+		t.Errorf("Get after Delete: expected ErrNotFound")
+	}
+	if ids, _ := s.List("users"); len(ids) != 0 {
+		t.Errorf("List of empty kind: got %v", ids)
+	}
+}
+
+func TestInvalidId(t *testing.T) {
+	dir, err := ioutil.TempDir("", "g2-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, _ := Open(dir)
+	for _, id := range []string{"", "../x", "a/b", ".hidden"} {
+		if err := s.Put("tickets", id, &doc{}); err == nil {
+			t.Errorf("Put(%q): expected error", id)
+		}
+	}
+}
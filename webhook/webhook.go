@@ -0,0 +1,427 @@
+// Package webhook notifies other systems (e.g. an applicant tracking
+// system) about ticket lifecycle events.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/gommon/log"
+	"github.com/maddyonline/g2/logging"
+	"github.com/maddyonline/g2/store"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Kind string
+
+const (
+	TICKET_CREATED   Kind = "ticket.created"
+	SESSION_STARTED  Kind = "session.started"
+	FINAL_SUBMITTED  Kind = "final.submitted"
+	TICKET_TIMED_OUT Kind = "ticket.timed_out"
+	PING             Kind = "ping"
+)
+
+var kinds = []Kind{TICKET_CREATED, SESSION_STARTED, FINAL_SUBMITTED, TICKET_TIMED_OUT, PING}
+
+func (k Kind) Valid() bool {
+	for _, kind := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	SignatureHeader = "X-G2-Signature"
+	EventHeader     = "X-G2-Event"
+	DeliveryHeader  = "X-G2-Delivery"
+)
+
+// Hook is a URL that gets POSTed the events it subscribed to.
+type Hook struct {
+	Id      string    `json:"id"`
+	URL     string    `json:"url"`
+	Secret  string    `json:"secret"`
+	Events  []Kind    `json:"events"`
+	Created time.Time `json:"created"`
+}
+
+// Wants reports whether the hook subscribed to kind. Hooks without a list
+// of events get all of them.
+func (h *Hook) Wants(kind Kind) bool {
+	if len(h.Events) == 0 || kind == PING {
+		return true
+	}
+	for _, k := range h.Events {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is the JSON body of every webhook request.
+type Event struct {
+	Id   string          `json:"id"`
+	Kind Kind            `json:"event"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+type Attempt struct {
+	Time   time.Time `json:"time"`
+	Status int       `json:"status,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Delivery is the log of sending one event to one hook. NextAttempt is when
+// an undelivered one is retried; once retries run out, it is Abandoned.
+type Delivery struct {
+	Id          string     `json:"id"`
+	Hook        string     `json:"hook"`
+	Event       string     `json:"event"`
+	Kind        Kind       `json:"kind"`
+	Created     time.Time  `json:"created"`
+	Attempts    []*Attempt `json:"attempts"`
+	Delivered   bool       `json:"delivered"`
+	NextAttempt time.Time  `json:"next_attempt,omitempty"`
+	Abandoned   bool       `json:"abandoned,omitempty"`
+}
+
+// Sign returns the value of the X-G2-Signature header for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify is what receivers do to check a request came from us.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+func randHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Dispatcher fires events to the registered hooks. Failed deliveries are
+// retried after each of Backoff in turn; the time of the next attempt is
+// saved, so that Resume carries on after a restart.
+type Dispatcher struct {
+	Store   *store.Store
+	Client  *http.Client
+	Backoff []time.Duration
+	pending sync.WaitGroup
+	stop    chan struct{}
+	stopped sync.Once
+	*sync.Mutex
+}
+
+func New(db *store.Store) *Dispatcher {
+	return &Dispatcher{
+		Store:   db,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+		Backoff: []time.Duration{10 * time.Second, time.Minute, 5 * time.Minute, 30 * time.Minute},
+		stop:    make(chan struct{}),
+		Mutex:   &sync.Mutex{},
+	}
+}
+
+func (d *Dispatcher) AddHook(h *Hook) (*Hook, error) {
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("Invalid webhook url %q", h.URL)
+	}
+	for _, kind := range h.Events {
+		if !kind.Valid() {
+			return nil, fmt.Errorf("Unknown event %q", kind)
+		}
+	}
+	if h.Id, err = randHex(8); err != nil {
+		return nil, err
+	}
+	if h.Secret == "" {
+		if h.Secret, err = randHex(32); err != nil {
+			return nil, err
+		}
+	}
+	h.Created = time.Now()
+	return h, d.Store.Put("webhooks", h.Id, h)
+}
+
+func (d *Dispatcher) GetHook(id string) (*Hook, error) {
+	h := &Hook{}
+	if err := d.Store.Get("webhooks", id, h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (d *Dispatcher) Hooks() ([]*Hook, error) {
+	ids, err := d.Store.List("webhooks")
+	if err != nil {
+		return nil, err
+	}
+	list := []*Hook{}
+	for _, id := range ids {
+		h, err := d.GetHook(id)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, h)
+	}
+	return list, nil
+}
+
+func (d *Dispatcher) DeleteHook(id string) error {
+	if _, err := d.GetHook(id); err != nil {
+		return err
+	}
+	return d.Store.Delete("webhooks", id)
+}
+
+// Fire sends an event about key (usually a ticket id) to every hook that
+// wants it. Each kind of event fires at most once per key; later calls
+// return a nil event.
+func (d *Dispatcher) Fire(kind Kind, key string, data interface{}) (*Event, error) {
+	id := fmt.Sprintf("%s-%s", kind, key)
+	d.Lock()
+	defer d.Unlock()
+	err := d.Store.Get("events", id, &Event{})
+	if err == nil {
+		return nil, nil
+	}
+	if _, ok := err.(store.ErrNotFound); !ok {
+		return nil, err
+	}
+	ev, err := newEvent(id, kind, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.Store.Put("events", id, ev); err != nil {
+		return nil, err
+	}
+	hooks, err := d.Hooks()
+	if err != nil {
+		return ev, err
+	}
+	for _, h := range hooks {
+		if h.Wants(kind) {
+			d.send(h, ev)
+		}
+	}
+	return ev, nil
+}
+
+// Ping sends a test event to h and returns its delivery.
+func (d *Dispatcher) Ping(h *Hook) (*Delivery, error) {
+	id, err := randHex(8)
+	if err != nil {
+		return nil, err
+	}
+	ev, err := newEvent(fmt.Sprintf("%s-%s", PING, id), PING, map[string]string{"hook": h.Id})
+	if err != nil {
+		return nil, err
+	}
+	return d.send(h, ev), nil
+}
+
+func newEvent(id string, kind Kind, data interface{}) (*Event, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Event{Id: id, Kind: kind, Time: time.Now(), Data: b}, nil
+}
+
+// send logs a delivery of ev to h and makes it in the background. The
+// returned delivery is a snapshot taken before the first attempt.
+func (d *Dispatcher) send(h *Hook, ev *Event) *Delivery {
+	del := &Delivery{
+		Id:      fmt.Sprintf("%s-%s", ev.Id, h.Id),
+		Hook:    h.Id,
+		Event:   ev.Id,
+		Kind:    ev.Kind,
+		Created: time.Now(),
+	}
+	d.save(del)
+	snapshot := *del
+	d.start(h, ev, del)
+	return &snapshot
+}
+
+// start makes the remaining attempts of del in the background.
+func (d *Dispatcher) start(h *Hook, ev *Event, del *Delivery) {
+	d.pending.Add(1)
+	go func() {
+		defer d.pending.Done()
+		d.deliver(h, ev, del)
+	}()
+}
+
+func (d *Dispatcher) save(del *Delivery) {
+	if err := d.Store.Put("deliveries", del.Id, del); err != nil {
+		log.Errorj(logging.Line("Saving delivery failed", "delivery_id", del.Id, "error", err.Error()))
+	}
+}
+
+// abandon gives up on del without another attempt.
+func (d *Dispatcher) abandon(del *Delivery) {
+	del.NextAttempt, del.Abandoned = time.Time{}, true
+	d.save(del)
+}
+
+func (d *Dispatcher) deliver(h *Hook, ev *Event, del *Delivery) {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		log.Errorj(logging.Line("Encoding event failed", "event_id", ev.Id, "delivery_id", del.Id, "error", err.Error()))
+		d.abandon(del)
+		return
+	}
+	for {
+		if wait := del.NextAttempt.Sub(time.Now()); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-d.stop:
+				// The next attempt is saved for Resume.
+				return
+			}
+		}
+		attempt := d.post(h, ev, del.Id, body)
+		del.Attempts = append(del.Attempts, attempt)
+		del.Delivered = attempt.Error == ""
+		if del.Delivered {
+			del.NextAttempt = time.Time{}
+			d.save(del)
+			return
+		}
+		n := len(del.Attempts)
+		if n > len(d.Backoff) {
+			log.Warnj(logging.Line("Gave up on delivery", "delivery_id", del.Id, "hook_id", h.Id, "url", h.URL, "error", attempt.Error))
+			d.abandon(del)
+			return
+		}
+		del.NextAttempt = attempt.Time.Add(d.Backoff[n-1])
+		d.save(del)
+	}
+}
+
+// Resume restarts the deliveries an earlier run left undelivered, each at
+// the time of its next attempt. Those whose hook or event is gone, such as
+// pings, which are not stored, or whose retries ran out are abandoned.
+func (d *Dispatcher) Resume() (int, error) {
+	ids, err := d.Store.List("deliveries")
+	if err != nil {
+		return 0, err
+	}
+	resumed := 0
+	for _, id := range ids {
+		del := &Delivery{}
+		if err := d.Store.Get("deliveries", id, del); err != nil {
+			return resumed, err
+		}
+		if del.Delivered || del.Abandoned {
+			continue
+		}
+		if len(del.Attempts) > len(d.Backoff) {
+			d.abandon(del)
+			continue
+		}
+		h, err := d.GetHook(del.Hook)
+		ev := &Event{}
+		if err == nil {
+			err = d.Store.Get("events", del.Event, ev)
+		}
+		if _, ok := err.(store.ErrNotFound); ok {
+			log.Warnj(logging.Line("Abandoned delivery", "delivery_id", del.Id, "error", err.Error()))
+			d.abandon(del)
+			continue
+		}
+		if err != nil {
+			return resumed, err
+		}
+		d.start(h, ev, del)
+		resumed++
+	}
+	return resumed, nil
+}
+
+func (d *Dispatcher) post(h *Hook, ev *Event, deliveryId string, body []byte) *Attempt {
+	attempt := &Attempt{Time: time.Now()}
+	req, err := http.NewRequest("POST", h.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(h.Secret, body))
+	req.Header.Set(EventHeader, string(ev.Kind))
+	req.Header.Set(DeliveryHeader, deliveryId)
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	attempt.Status = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = resp.Status
+	}
+	return attempt
+}
+
+// Stop ends the retries waiting for their turn, leaving them to Resume.
+// First attempts are still made.
+func (d *Dispatcher) Stop() {
+	d.stopped.Do(func() { close(d.stop) })
+}
+
+// Wait blocks until every delivery in flight succeeded, was given up or,
+// once stopped, is waiting for a retry.
+func (d *Dispatcher) Wait() {
+	d.pending.Wait()
+}
+
+// byCreated orders deliveries newest first.
+type byCreated []*Delivery
+
+func (a byCreated) Len() int           { return len(a) }
+func (a byCreated) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byCreated) Less(i, j int) bool { return a[i].Created.After(a[j].Created) }
+
+// Deliveries returns the delivery log of a hook, newest first.
+func (d *Dispatcher) Deliveries(hookId string) ([]*Delivery, error) {
+	ids, err := d.Store.List("deliveries")
+	if err != nil {
+		return nil, err
+	}
+	list := []*Delivery{}
+	for _, id := range ids {
+		del := &Delivery{}
+		if err := d.Store.Get("deliveries", id, del); err != nil {
+			return nil, err
+		}
+		if del.Hook == hookId {
+			list = append(list, del)
+		}
+	}
+	sort.Sort(byCreated(list))
+	return list, nil
+}
@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"encoding/json"
+	"github.com/maddyonline/g2/store"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver stands in for the ATS: it fails the first failures requests and
+// records the events it accepted.
+type receiver struct {
+	secret   string
+	failures int
+	events   []*Event
+	*sync.Mutex
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+	body, _ := ioutil.ReadAll(req.Body)
+	if !Verify(r.secret, body, req.Header.Get(SignatureHeader)) {
+		http.Error(w, "bad signature", http.StatusUnauthorized)
+		return
+	}
+	if r.failures > 0 {
+		r.failures--
+		http.Error(w, "try again", http.StatusServiceUnavailable)
+		return
+	}
+	ev := &Event{}
+	json.Unmarshal(body, ev)
+	r.events = append(r.events, ev)
+}
+
+func newDispatcher(t *testing.T) (*Dispatcher, func()) {
+	dir, err := ioutil.TempDir("", "g2-webhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, _ := store.Open(dir)
+	d := New(db)
+	d.Backoff = []time.Duration{time.Millisecond, time.Millisecond}
+	return d, func() { os.RemoveAll(dir) }
+}
+
+func TestFire(t *testing.T) {
+	d, cleanup := newDispatcher(t)
+	defer cleanup()
+	rcv := &receiver{secret: "s3cret", failures: 1, Mutex: &sync.Mutex{}}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	h, err := d.AddHook(&Hook{URL: srv.URL, Secret: "s3cret", Events: []Kind{TICKET_CREATED}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Fire(TICKET_CREATED, "t1", map[string]string{"ticket": "t1"}); err != nil {
+		t.Fatal(err)
+	}
+	if ev, _ := d.Fire(TICKET_CREATED, "t1", nil); ev != nil {
+		t.Error("an event fired twice for the same ticket")
+	}
+	d.Fire(SESSION_STARTED, "t1", nil)
+	d.Wait()
+
+	if len(rcv.events) != 1 || rcv.events[0].Kind != TICKET_CREATED || string(rcv.events[0].Data) != `{"ticket":"t1"}` {
+		t.Fatalf("received %+v", rcv.events)
+	}
+	log, err := d.Deliveries(h.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 1 || !log[0].Delivered || len(log[0].Attempts) != 2 || log[0].Attempts[0].Status != http.StatusServiceUnavailable {
+		t.Errorf("delivery log: %+v", log)
+	}
+}
+
+func TestGiveUp(t *testing.T) {
+	d, cleanup := newDispatcher(t)
+	defer cleanup()
+	rcv := &receiver{secret: "other", Mutex: &sync.Mutex{}}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	h, err := d.AddHook(&Hook{URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Secret == "" {
+		t.Error("a secret should have been generated")
+	}
+	if _, err := d.Ping(h); err != nil {
+		t.Fatal(err)
+	}
+	d.Wait()
+	log, _ := d.Deliveries(h.Id)
+	if len(log) != 1 || log[0].Delivered || len(log[0].Attempts) != 3 || log[0].Attempts[2].Status != http.StatusUnauthorized {
+		t.Errorf("delivery log: %+v", log)
+	}
+}
+
+func TestAddHook(t *testing.T) {
+	d, cleanup := newDispatcher(t)
+	defer cleanup()
+	for _, h := range []*Hook{
+		{URL: "ftp://example.com/"},
+		{URL: "not a url"},
+		{URL: "https://example.com/", Events: []Kind{"ticket.deleted"}},
+	} {
+		if _, err := d.AddHook(h); err == nil {
+			t.Errorf("AddHook(%+v) should fail", h)
+		}
+	}
+}
+
+func TestResume(t *testing.T) {
+	d, cleanup := newDispatcher(t)
+	defer cleanup()
+	rcv := &receiver{secret: "s3cret", Mutex: &sync.Mutex{}}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+	h, err := d.AddHook(&Hook{URL: srv.URL, Secret: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// What a restart leaves behind: a delivery waiting for a retry, one of
+	// a ping and one whose retries ran out.
+	ev, _ := newEvent("ticket.created-t1", TICKET_CREATED, nil)
+	d.Store.Put("events", ev.Id, ev)
+	failed := []*Attempt{{Time: time.Now(), Status: http.StatusServiceUnavailable, Error: "503"}}
+	d.save(&Delivery{Id: "retry", Hook: h.Id, Event: ev.Id, Kind: ev.Kind, Attempts: failed, NextAttempt: time.Now()})
+	d.save(&Delivery{Id: "ping", Hook: h.Id, Event: "ping-1", Kind: PING, Attempts: failed, NextAttempt: time.Now()})
+	d.save(&Delivery{Id: "given-up", Hook: h.Id, Event: ev.Id, Kind: ev.Kind, Attempts: []*Attempt{failed[0], failed[0], failed[0]}})
+
+	if n, err := d.Resume(); n != 1 || err != nil {
+		t.Fatalf("Resume() = %d, %v", n, err)
+	}
+	d.Wait()
+	if len(rcv.events) != 1 || rcv.events[0].Id != ev.Id {
+		t.Errorf("received %+v", rcv.events)
+	}
+	for id, delivered := range map[string]bool{"retry": true, "ping": false, "given-up": false} {
+		del := &Delivery{}
+		d.Store.Get("deliveries", id, del)
+		if del.Delivered != delivered || del.Abandoned == delivered || !del.NextAttempt.IsZero() {
+			t.Errorf("%s: %+v", id, del)
+		}
+	}
+	if n, _ := d.Resume(); n != 0 {
+		t.Errorf("resumed %d finished deliveries", n)
+	}
+}
+
+func TestStop(t *testing.T) {
+	d, cleanup := newDispatcher(t)
+	defer cleanup()
+	d.Backoff = []time.Duration{time.Hour}
+	rcv := &receiver{secret: "s3cret", failures: 2, Mutex: &sync.Mutex{}}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+	h, err := d.AddHook(&Hook{URL: srv.URL, Secret: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Fire(TICKET_CREATED, "t1", nil)
+	d.Stop()
+	// Fired after stopping, still attempted once.
+	d.Fire(SESSION_STARTED, "t1", nil)
+	waited := make(chan struct{})
+	go func() {
+		d.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait waited for a retry")
+	}
+	log, _ := d.Deliveries(h.Id)
+	if len(log) != 2 {
+		t.Fatalf("delivery log: %+v", log)
+	}
+	for _, del := range log {
+		if len(del.Attempts) != 1 || del.Delivered || del.Abandoned || del.NextAttempt.IsZero() {
+			t.Errorf("%s: %+v", del.Id, del)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"github.com/labstack/echo"
+	"github.com/labstack/gommon/log"
+	"github.com/maddyonline/g2/cui"
+	"github.com/maddyonline/g2/logging"
+	"github.com/maddyonline/g2/store"
+	"github.com/maddyonline/g2/webhook"
+	"net/http"
+	"time"
+)
+
+var hooks *webhook.Dispatcher
+
+// notify fires a ticket lifecycle event; the payload is the ticket summary.
+func notify(kind webhook.Kind, session *cui.Session) {
+	summary := summarize(session)
+	if _, err := hooks.Fire(kind, summary.Id, summary); err != nil {
+		log.Errorj(logging.Line("Firing webhook failed", logging.TICKET_ID, summary.Id, "event", string(kind), "error", err.Error()))
+	}
+}
+
+func notifyFinal(session *cui.Session, sub *cui.Submission) {
+	summary := summarize(session)
+	data := map[string]interface{}{"ticket": summary, "submission": sub}
+	key := fmt.Sprintf("%s-%s", summary.Id, sub.Id)
+	if _, err := hooks.Fire(webhook.FINAL_SUBMITTED, key, data); err != nil {
+		log.Errorj(logging.Line("Firing webhook failed", logging.TICKET_ID, summary.Id, "event", string(webhook.FINAL_SUBMITTED), "error", err.Error()))
+	}
+}
+
+// watchTimeouts notices tickets running out of time, which otherwise only
+// happens when someone looks at them.
+func watchTimeouts(interval time.Duration) {
+	notified := map[string]bool{}
+	for range time.Tick(interval) {
+		stateLock.Lock()
+		sessions := []*cui.Session{}
+		for id, session := range cuiSessions {
+			if !notified[id] {
+				sessions = append(sessions, session)
+			}
+		}
+		stateLock.Unlock()
+		for _, session := range sessions {
+			if session.Refresh() == cui.TIMEDOUT {
+				notify(webhook.TICKET_TIMED_OUT, session)
+				notified[session.Ticket.Id] = true
+			}
+		}
+	}
+}
+
+func getHook(c echo.Context) (*webhook.Hook, error) {
+	h, err := hooks.GetHook(c.Param("hook_id"))
+	if _, ok := err.(store.ErrNotFound); ok {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "No such webhook")
+	}
+	return h, err
+}
+
+func addWebhookAdminHandlers(api *echo.Group, mw ...echo.MiddlewareFunc) {
+	api.Get("/webhooks", func(c echo.Context) error {
+		list, err := hooks.Hooks()
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, list)
+	}, mw...)
+
+	api.Post("/webhooks", func(c echo.Context) error {
+		h := &webhook.Hook{}
+		if err := c.Bind(h); err != nil {
+			return err
+		}
+		h, err := hooks.AddHook(h)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return c.JSON(http.StatusCreated, h)
+	}, mw...)
+
+	api.Delete("/webhooks/:hook_id", func(c echo.Context) error {
+		h, err := getHook(c)
+		if err != nil {
+			return err
+		}
+		if err := hooks.DeleteHook(h.Id); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	}, mw...)
+
+	api.Post("/webhooks/:hook_id/ping", func(c echo.Context) error {
+		h, err := getHook(c)
+		if err != nil {
+			return err
+		}
+		del, err := hooks.Ping(h)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusAccepted, del)
+	}, mw...)
+
+	api.Get("/webhooks/:hook_id/deliveries", func(c echo.Context) error {
+		h, err := getHook(c)
+		if err != nil {
+			return err
+		}
+		list, err := hooks.Deliveries(h.Id)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, list)
+	}, mw...)
+}